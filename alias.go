@@ -0,0 +1,85 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Loads the aliases defined by the user in the file at path, e.g.
+// ~/.toolrc, one per line as name = expansion:
+//
+//	# build and test a release
+//	ci = build -release test
+//
+// The expansion is split by SplitArgs. When the first argument of
+// Parse is an alias, it is replaced by its expansion, which may start
+// with another alias. Sub-commands take precedence over aliases of
+// the same name. Blank lines and lines starting with # are skipped.
+func (c *Commands) LoadUserAliases(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if c.userAliases == nil {
+		c.userAliases = make(map[string][]string)
+	}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("%s:%d: 别名的格式应为 name = expansion", path, n)
+		}
+		name := strings.TrimSpace(line[:i])
+		expansion, err := SplitArgs(line[i+1:])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		if name == "" || len(expansion) == 0 {
+			return fmt.Errorf("%s:%d: 别名的格式应为 name = expansion", path, n)
+		}
+		c.userAliases[name] = expansion
+	}
+	return scanner.Err()
+}
+
+// Replaces the alias args starts with by its expansion, repeatedly,
+// returning an error if the aliases expand in a loop.
+func (c *Commands) expandAliases(args []string) ([]string, error) {
+	var seen []string
+	for len(args) > 0 && c.lookup(args[0]) == nil {
+		expansion, ok := c.userAliases[args[0]]
+		if !ok {
+			break
+		}
+		for _, name := range seen {
+			if name == args[0] {
+				return nil, fmt.Errorf("别名存在循环: %s -> %s", strings.Join(seen, " -> "), args[0])
+			}
+		}
+		seen = append(seen, args[0])
+		args = append(append([]string{}, expansion...), args[1:]...)
+	}
+	return args, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Writes the aliases to a file, returning its path.
+func writeAliases(t *testing.T, aliases string) string {
+	path := filepath.Join(t.TempDir(), ".toolrc")
+	if err := os.WriteFile(path, []byte(aliases), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Tests if an alias is expanded before matching the command.
+func TestUserAliases(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+	path := writeAliases(t, "# shortcuts\n\nc1 = command1 -flag1 \"a b\"\nquick = c1 c\ncommand2 = command1\n")
+	if err := c.LoadUserAliases(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.parse([]string{"quick", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.matchingCmd.name != "command1" || !*c1.flag1 || !reflect.DeepEqual(c.args, []string{"a b", "c", "d"}) {
+		t.Errorf("expected command1 -flag1 with [a b c d], found %s with %q", c.matchingCmd.name, c.args)
+	}
+
+	if err := c.parse([]string{"command2"}); err != nil || c.matchingCmd.name != "command2" {
+		t.Errorf("the command is expected to take precedence over the alias, found %v", err)
+	}
+
+	if err := c.LoadUserAliases(writeAliases(t, "broken\n")); err == nil {
+		t.Error("a line without = is expected to fail")
+	}
+}
+
+// Tests if aliases expanding in a loop are reported.
+func TestUserAliasesLoop(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	if err := c.LoadUserAliases(writeAliases(t, "a = b -x\nb = a -y\n")); err != nil {
+		t.Fatal(err)
+	}
+	err := c.parse([]string{"a"})
+	if err == nil || err.problems[0].Kind != KindAliasLoop || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected the loop to be reported, found %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Populates the struct pointed to by v with the positional arguments.
+// A field tagged `arg:"0"` receives the first argument, `arg:"1"` the
+// second and so on; a slice field tagged `arg:"..."` collects whatever is
+// left. Arguments are converted to the field's type, and an error is
+// returned if there are too few or too many of them.
+//
+//	var a struct {
+//		Src   string   `arg:"0"`
+//		Count int      `arg:"1"`
+//		Rest  []string `arg:"..."`
+//	}
+//	err := command.BindArgs(args, &a)
+func BindArgs(args []string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("BindArgs 的参数必须是结构体指针")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var fixed []int
+	variadic := -1
+	for i := 0; i < rt.NumField(); i++ {
+		tag, ok := rt.Field(i).Tag.Lookup("arg")
+		if !ok {
+			continue
+		}
+		if !rt.Field(i).IsExported() {
+			return fmt.Errorf("字段 '%s' 未导出, 不能作为参数", rt.Field(i).Name)
+		}
+		if tag == "..." {
+			if rt.Field(i).Type.Kind() != reflect.Slice {
+				return fmt.Errorf("字段 '%s' 必须是切片类型", rt.Field(i).Name)
+			}
+			variadic = i
+			continue
+		}
+		idx, err := strconv.Atoi(tag)
+		if err != nil || idx < 0 {
+			return fmt.Errorf("字段 '%s' 的 arg 标签 '%s' 无效", rt.Field(i).Name, tag)
+		}
+		for len(fixed) <= idx {
+			fixed = append(fixed, -1)
+		}
+		if fixed[idx] >= 0 {
+			return fmt.Errorf("参数 %d 对应了多个字段", idx)
+		}
+		fixed[idx] = i
+	}
+	for idx, field := range fixed {
+		if field < 0 {
+			return fmt.Errorf("参数 %d 没有对应的字段", idx)
+		}
+	}
+
+	if len(args) < len(fixed) {
+		return fmt.Errorf("参数太少: 需要 %d 个, 实际 %d 个", len(fixed), len(args))
+	}
+	if variadic < 0 && len(args) > len(fixed) {
+		return fmt.Errorf("参数太多: 最多 %d 个, 实际 %d 个", len(fixed), len(args))
+	}
+
+	for idx, field := range fixed {
+		if err := setArg(rv.Field(field), args[idx]); err != nil {
+			return fmt.Errorf("参数 %d (%s): %s", idx, rt.Field(field).Name, err)
+		}
+	}
+	if variadic >= 0 {
+		rest := args[len(fixed):]
+		slice := reflect.MakeSlice(rt.Field(variadic).Type, len(rest), len(rest))
+		for i, s := range rest {
+			if err := setArg(slice.Index(i), s); err != nil {
+				return fmt.Errorf("参数 %d (%s): %s", len(fixed)+i, rt.Field(variadic).Name, err)
+			}
+		}
+		rv.Field(variadic).Set(slice)
+	}
+	return nil
+}
+
+// Converts s to the type of v and stores it.
+func setArg(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("不支持的类型 %s", v.Type())
+	}
+	return nil
+}
+
+// Splits s into arguments the way a POSIX shell does, without any
+// expansion: arguments are separated by whitespace, single quotes keep
+// everything up to the next single quote, and double quotes keep
+// everything up to the next double quote except that a backslash
+// escapes ", \, $ and `. Outside of quotes a backslash escapes the
+// next character. An error is returned for an unterminated quote.
+//
+//	args, err := command.SplitArgs(`deploy -m "first release" -f 'a b'`)
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var arg []rune
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				arg = append(arg, '\\')
+			}
+			arg = append(arg, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg = append(arg, r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = arg[:0], false
+			}
+		default:
+			arg = append(arg, r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("缺少匹配的引号 %c", quote)
+	}
+	if escaped {
+		return nil, errors.New("末尾的反斜杠没有转义任何字符")
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
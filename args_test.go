@@ -0,0 +1,136 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests if fixed positional arguments are bound to their fields.
+func TestBindArgsFixed(t *testing.T) {
+	var a struct {
+		Src string `arg:"0"`
+		Dst string `arg:"1"`
+	}
+	if err := BindArgs([]string{"a.txt", "b.txt"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Src != "a.txt" || a.Dst != "b.txt" {
+		t.Errorf("expected a.txt and b.txt, found %s and %s", a.Src, a.Dst)
+	}
+
+	if err := BindArgs([]string{"a.txt"}, &a); err == nil {
+		t.Error("too few arguments are expected to fail")
+	}
+	if err := BindArgs([]string{"a.txt", "b.txt", "c.txt"}, &a); err == nil {
+		t.Error("too many arguments are expected to fail")
+	}
+}
+
+// Tests if the remaining arguments are collected by the variadic field.
+func TestBindArgsVariadic(t *testing.T) {
+	var a struct {
+		Name  string `arg:"0"`
+		Ports []int  `arg:"..."`
+	}
+	if err := BindArgs([]string{"web", "80", "443"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "web" || len(a.Ports) != 2 || a.Ports[0] != 80 || a.Ports[1] != 443 {
+		t.Errorf("expected web [80 443], found %s %v", a.Name, a.Ports)
+	}
+
+	if err := BindArgs([]string{"web"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Ports) != 0 {
+		t.Errorf("expected no ports, found %v", a.Ports)
+	}
+
+	if err := BindArgs([]string{"web", "http"}, &a); err == nil {
+		t.Error("a non-numeric port is expected to fail")
+	}
+}
+
+// Tests if arguments are converted to the field types.
+func TestBindArgsConversion(t *testing.T) {
+	var a struct {
+		Count   int           `arg:"0"`
+		Force   bool          `arg:"1"`
+		Ratio   float64       `arg:"2"`
+		Timeout time.Duration `arg:"3"`
+		Size    uint          `arg:"4"`
+	}
+	if err := BindArgs([]string{"-3", "true", "0.5", "1m30s", "0x10"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count != -3 || !a.Force || a.Ratio != 0.5 || a.Timeout != 90*time.Second || a.Size != 16 {
+		t.Errorf("unexpected conversion result: %+v", a)
+	}
+
+	if err := BindArgs([]string{"x", "true", "0.5", "1s", "1"}, &a); err == nil {
+		t.Error("a non-numeric count is expected to fail")
+	}
+	if err := BindArgs(nil, a); err == nil {
+		t.Error("a non-pointer value is expected to fail")
+	}
+}
+
+// Tests if an unexported tagged field fails instead of panicking.
+func TestBindArgsUnexported(t *testing.T) {
+	var a struct {
+		Src  string `arg:"0"`
+		dest string `arg:"1"`
+	}
+	err := BindArgs([]string{"a", "b"}, &a)
+	if err == nil || !strings.Contains(err.Error(), "dest") {
+		t.Errorf("expected the unexported dest to fail, found %v", err)
+	}
+	if a.Src != "" || a.dest != "" {
+		t.Errorf("no field is expected to be set, found %+v", a)
+	}
+}
+
+// Tests if a string is split into arguments like a shell does.
+func TestSplitArgs(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected []string
+	}{
+		{"", nil},
+		{"  deploy   -env prod ", []string{"deploy", "-env", "prod"}},
+		{`-m "first release" -f 'a b'`, []string{"-m", "first release", "-f", "a b"}},
+		{`a\ b "c\"d" 'e\f' "g\h"`, []string{"a b", `c"d`, `e\f`, `g\h`}},
+		{`"" x''y`, []string{"", "xy"}},
+	} {
+		args, err := SplitArgs(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%q: expected %q, found %q", test.s, test.expected, args)
+		}
+	}
+
+	for _, s := range []string{`"open`, `'open`, `end\`} {
+		if _, err := SplitArgs(s); err == nil {
+			t.Errorf("%q is expected to fail", s)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+// Result is the outcome of an invocation run by Batch.
+type Result struct {
+	Args []string
+	// The code the invocation would exit with, 0 on success.
+	Code int
+	// The failure of parsing or the error of the sub-command.
+	Err error
+}
+
+// Parses and runs each invocation in turn like ParseAndRun, without
+// exiting, and returns their results. With stopOnError, it stops after
+// the first failing invocation, whose result is the last one. The
+// failures are reported as usual.
+func (c *Commands) Batch(invocations [][]string, stopOnError bool) []Result {
+	var results []Result
+	for _, args := range invocations {
+		result := Result{Args: args}
+		if e := c.parse(args); e != nil {
+			c.reportParseError(e)
+			result.Code, result.Err = e.code, e.asError()
+		} else {
+			result.Code, result.Err = c.run()
+		}
+		results = append(results, result)
+		if result.Err != nil && stopOnError {
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,59 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"testing"
+)
+
+// Tests if the invocations run in turn, stopping at the first failure
+// only if asked to, and never exit.
+func TestBatch(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	errFail := &Error{Code: 3, Message: "boom"}
+	c.On("fail", "", &testErrCmd{err: errFail}, []string{})
+	invocations := [][]string{
+		{"command1"},
+		{"unknown"},
+		{"fail"},
+		{"command1", "-flag1"},
+	}
+
+	results := c.Batch(invocations, false)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, found %d", len(results))
+	}
+	for i, expected := range []int{0, c.usageCode(), 3, 0} {
+		if results[i].Code != expected || (results[i].Err != nil) != (expected != 0) {
+			t.Errorf("%v: expected code %d, found %d and %v", results[i].Args, expected, results[i].Code, results[i].Err)
+		}
+	}
+	if results[2].Err != errFail {
+		t.Errorf("expected the error of the command, found %v", results[2].Err)
+	}
+
+	results = c.Batch(invocations, true)
+	if len(results) != 2 || results[1].Args[0] != "unknown" {
+		t.Errorf("expected to stop at the unknown command, found %v", results)
+	}
+	if *code != -100 {
+		t.Errorf("no exit is expected, found %d", *code)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+)
+
+// checkReport is printed by Parse in check mode instead of running the
+// matching sub-command.
+type checkReport struct {
+	Command  string            `json:"command,omitempty"`
+	Flags    map[string]string `json:"flags,omitempty"`
+	Args     []string          `json:"args"`
+	Valid    bool              `json:"valid"`
+	Problems []problem         `json:"problems,omitempty"`
+}
+
+// Defines a global flag named name enabling the check mode: Parse
+// prints a JSON report to StdOutput with the sub-command which would
+// run, the flags set on the command line, the arguments, and the
+// problems found while parsing if any, then exits with 0 without
+// running anything, or returns with nothing to run if the error
+// handling is flag.ContinueOnError, see SetErrorHandling. Neither
+// prompts nor reads the input in check mode.
+func (c *Commands) EnableCheckMode(name string) {
+	c.flags.BoolVar(&c.checkMode, name, false, "只检查参数, 输出将要运行的命令而不运行")
+}
+
+// Prints the report of the check mode for the parse which failed with
+// e, or succeeded if e is nil.
+func (c *Commands) reportCheck(e *parseError) {
+	report := checkReport{Args: c.args, Valid: e == nil}
+	subcmd := c.matchingCmd
+	if e != nil {
+		subcmd = e.subcmd
+	}
+	if subcmd != nil {
+		report.Command = subcmd.name
+	}
+	if c.matchingFlagSet != nil {
+		report.Flags = make(map[string]string)
+		c.matchingFlagSet.Visit(func(f *flag.Flag) {
+			report.Flags[f.Name] = f.Value.String()
+			if c.isSensitive(subcmd, f.Name) {
+				report.Flags[f.Name] = redacted
+			}
+		})
+	}
+	if report.Args == nil {
+		report.Args = []string{}
+	}
+	if e != nil {
+		report.Problems = e.problems
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	Println(string(data))
+}
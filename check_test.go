@@ -0,0 +1,105 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// Tests if the check mode reports what would run without running it.
+func TestCheckMode(t *testing.T) {
+	stdout := captureStdOutput(t)
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{"flag1"})
+	c.EnableCheckMode("check")
+	if err := c.flags.Parse([]string{"-check"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.ParseAndRun([]string{"command1", "-flag1", "a", "b"})
+	if c1.run {
+		t.Error("command1 is not expected to run in check mode")
+	}
+	if *code != 0 {
+		t.Errorf("expected exit code 0, found %v", *code)
+	}
+	var report checkReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	expected := checkReport{
+		Command: "command1",
+		Flags:   map[string]string{"flag1": "true"},
+		Args:    []string{"a", "b"},
+		Valid:   true,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, found %+v", expected, report)
+	}
+
+	stdout.Reset()
+	*code = -100
+	c.ParseAndRun([]string{"command1", "a"})
+	if c1.run {
+		t.Error("command1 is not expected to run in check mode")
+	}
+	if *code != 0 {
+		t.Errorf("expected exit code 0, found %v", *code)
+	}
+	report = checkReport{}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Command != "command1" || report.Valid || len(report.Problems) != 1 ||
+		report.Problems[0].Kind != KindMissingRequiredFlag ||
+		!reflect.DeepEqual(report.Problems[0].Flags, []string{"flag1"}) {
+		t.Errorf("expected the missing flag1 to be reported, found %+v", report)
+	}
+}
+
+// Tests if the check mode returns instead of exiting when the error
+// handling is flag.ContinueOnError.
+func TestCheckModeContinueOnError(t *testing.T) {
+	stdout := captureStdOutput(t)
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{"flag1"})
+	c.SetErrorHandling(flag.ContinueOnError)
+	c.EnableCheckMode("check")
+	if err := c.flags.Parse([]string{"-check"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.ParseAndRun([]string{"command1", "a"})
+	if *code != -100 || c1.run {
+		t.Errorf("expected to return without running, found run %v, exit code %v", c1.run, *code)
+	}
+	if err, _ := c.Err(); err != nil {
+		t.Errorf("the check is not expected to fail, found %v", err)
+	}
+	if stdout.Len() == 0 {
+		t.Error("expected the report")
+	}
+}
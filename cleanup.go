@@ -0,0 +1,46 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+// Registers fn to run when the running subcommand finishes, whether it
+// returns or the program exits through Exit while it runs. Cleanups
+// run in the reverse order of registration, like deferred calls.
+// Exiting with os.Exit directly skips them.
+func (c *Commands) OnCleanup(fn func()) {
+	c.cleanups = append(c.cleanups, fn)
+}
+
+// Replaces Exit with one running the cleanups before exiting, returning
+// a func which restores Exit and runs the cleanups left.
+func (c *Commands) trapExit() func() {
+	exit := Exit
+	Exit = func(code int) {
+		c.runCleanups()
+		exit(code)
+	}
+	return func() {
+		Exit = exit
+		c.runCleanups()
+	}
+}
+
+// Runs the registered cleanups in reverse order, each only once.
+func (c *Commands) runCleanups() {
+	for len(c.cleanups) > 0 {
+		fn := c.cleanups[len(c.cleanups)-1]
+		c.cleanups = c.cleanups[:len(c.cleanups)-1]
+		fn()
+	}
+}
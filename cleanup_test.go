@@ -0,0 +1,72 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// testCleanupCmd is a test sub command registering two cleanups, and
+// exiting with exit if it isn't 0.
+type testCleanupCmd struct {
+	c     *Commands
+	exit  int
+	calls []string
+}
+
+func (cmd *testCleanupCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testCleanupCmd) Run(args []string) error {
+	cmd.c.OnCleanup(func() { cmd.calls = append(cmd.calls, "first") })
+	cmd.c.OnCleanup(func() { cmd.calls = append(cmd.calls, "second") })
+	if cmd.exit != 0 {
+		Exit(cmd.exit)
+	}
+	cmd.calls = append(cmd.calls, "returned")
+	return nil
+}
+
+// Tests if the cleanups run in reverse order on return and on Exit.
+func TestOnCleanup(t *testing.T) {
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testCleanupCmd{c: c}
+	c.On("command1", "", cmd, []string{})
+	c.ParseAndRun([]string{"command1"})
+	if expected := []string{"returned", "second", "first"}; !reflect.DeepEqual(cmd.calls, expected) {
+		t.Errorf("expected %v, found %v", expected, cmd.calls)
+	}
+
+	cmd.calls, cmd.exit = nil, 3
+	c.ParseAndRun([]string{"command1"})
+	if *code != 3 {
+		t.Errorf("expected exit code 3, found %d", *code)
+	}
+	// The exit in the test returns, so the command goes on after it.
+	if expected := []string{"second", "first", "returned"}; !reflect.DeepEqual(cmd.calls, expected) {
+		t.Errorf("expected %v, found %v", expected, cmd.calls)
+	}
+
+	*code = -100
+	Exit(4)
+	if *code != 4 || len(cmd.calls) != 3 {
+		t.Errorf("expected Exit to be restored, found code %d and calls %v", *code, cmd.calls)
+	}
+}
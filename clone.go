@@ -0,0 +1,127 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import "reflect"
+
+// Cloner is implemented by sub-commands which keep state, e.g. the
+// values their flags are bound to, so that each Clone gets its own.
+// CloneCmd returns a new command of the same type, which is shared
+// otherwise.
+type Cloner interface {
+	CloneCmd() interface{}
+}
+
+// Returns a copy of the sub-commands registered on c, owned by clone
+// and belonging to the group parent, if any.
+func (c *Commands) cloneList(clone *Commands, parent *cmdInstance) []*cmdInstance {
+	list := make([]*cmdInstance, 0, len(c.list))
+	for _, subcmd := range c.list {
+		list = append(list, subcmd.clone(clone, parent))
+	}
+	return list
+}
+
+// Returns a copy of the sub-command registered on c, with its own
+// metadata and command.
+func (subcmd *cmdInstance) clone(c *Commands, parent *cmdInstance) *cmdInstance {
+	clone := *subcmd
+	clone.parent = parent
+	clone.requiredFlags = append([]string(nil), subcmd.requiredFlags...)
+	clone.forwardArgs = append([]string(nil), subcmd.forwardArgs...)
+	clone.categories = append([]string(nil), subcmd.categories...)
+	clone.constraints = append([]Constraint(nil), subcmd.constraints...)
+	clone.examples = append([]string(nil), subcmd.examples...)
+	clone.defaultArgs = append([]string(nil), subcmd.defaultArgs...)
+	clone.aliases = append([]string(nil), subcmd.aliases...)
+	clone.annotations = copyStrings(subcmd.annotations)
+	clone.sensitiveFlags = copyBools(subcmd.sensitiveFlags)
+	if subcmd.allowedArgs != nil {
+		clone.allowedArgs = make(map[int][]string, len(subcmd.allowedArgs))
+		for i, values := range subcmd.allowedArgs {
+			clone.allowedArgs[i] = append([]string(nil), values...)
+		}
+	}
+
+	if subcmd.group != nil {
+		group := *subcmd.group
+		group.parent = &clone
+		group.list = subcmd.group.cloneList(&group, &clone)
+		group.sensitiveFlags = copyBools(subcmd.group.sensitiveFlags)
+		clone.group = &group
+		clone.command = &groupCmd{subcmd: &clone}
+		return &clone
+	}
+	// a forwarding sub-command has no command of its own
+	if subcmd.command != nil {
+		clone.command = cloneCmd(subcmd.command, c).(Cmd)
+	}
+	return &clone
+}
+
+// Returns the command of a clone owned by c: the built-in commands are
+// bound to c, and the others are copied if they implement Cloner, else
+// shared.
+func cloneCmd(command interface{}, c *Commands) interface{} {
+	switch cmd := command.(type) {
+	case *envCmd:
+		return &envCmd{c: c}
+	case *examplesCmd:
+		return &examplesCmd{c: c}
+	case *selfTestCmd:
+		return &selfTestCmd{c: c}
+	case *versionCmd:
+		return &versionCmd{c: c}
+	case *scriptCmd:
+		return &scriptCmd{c: c, path: cmd.path}
+	case *outputCmd:
+		return &outputCmd{c: c, command: cloneCmd(cmd.command, c).(OutputCmd)}
+	case *structCmd:
+		v := reflect.New(cmd.init.Type())
+		v.Elem().Set(cmd.init)
+		clone := *cmd
+		clone.v = v.Elem()
+		clone.run = v.Interface().(interface{ Run(args []string) error }).Run
+		return &clone
+	case Cloner:
+		clone := cmd.CloneCmd()
+		if reflect.TypeOf(clone) == reflect.TypeOf(command) {
+			return clone
+		}
+	}
+	return command
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func copyBools(m map[string]bool) map[string]bool {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]bool, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
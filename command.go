@@ -18,16 +18,24 @@
 package command
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"errors"
+	"strings"
 )
 
+var StdInput io.Reader = os.Stdin
 var StdOutput io.Writer = os.Stdout
 var StdErr io.Writer = os.Stderr
 
+// Exit terminates the program with the given status code, it can be
+// replaced to keep a failing parse or run from exiting, e.g. in tests.
+var Exit = os.Exit
+
 func Println(args ...interface{}) {
 	fmt.Fprintln(StdOutput, args...)
 }
@@ -59,19 +67,198 @@ type Commands struct {
 	// A map of all of the registered sub-commands.
 	list []*cmdInstance
 
+	// The group the sub-commands belong to, see OnGroup.
+	parent *cmdInstance
+
 	// Matching subcommand.
 	matchingCmd *cmdInstance
 
+	// The subcommand which really runs, it differs from matchingCmd
+	// if the latter forwards to another subcommand.
+	matchingTarget *cmdInstance
+
+	// The parsed flags of the matching subcommand.
+	matchingFlagSet *flag.FlagSet
+
 	// Arguments to call subcommand's runnable.
 	args []string
 
 	// Flag to determine whether help is
 	// asked for subcommand or not
 	flagHelp bool
+
+	// Allows a subcommand requiring root to run without it.
+	flagAllowUnprivileged bool
+
+	// Runs a destructive subcommand without confirmation.
+	flagYes bool
+
+	// Suppresses the usage hint printed after an unknown sub-command.
+	noUsageHint bool
+
+	// Keeps the failures from printing the usage, see
+	// SetSuppressUsageOnError.
+	suppressUsage bool
+
+	// Suggests the sub-commands close to an unknown one, see
+	// SetSuggestions.
+	suggestions    bool
+	maxSuggestions int
+
+	// The subcommand to run if none is given, defaultToFirst picks
+	// the first registered one if defaultName isn't set.
+	defaultName    string
+	defaultToFirst bool
+
+	// The subcommand to run instead if none is given and firstRunDetect
+	// reports the first run, see SetFirstRunCommand.
+	firstRunName   string
+	firstRunDetect func() bool
+
+	// Prompts for missing required flags on a terminal.
+	promptRequired bool
+
+	// Prompts for the subcommand if none is given on a terminal, and
+	// guided is set while parsing the one chosen that way.
+	interactiveFallback bool
+	guided              bool
+
+	// The flag reading more arguments from StdInput, see
+	// EnableStdinArgs.
+	stdinArgsFlag string
+	stdinArgsNull bool
+
+	// Derives the exit code from an error returned by a subcommand.
+	exitCoder func(err error) int
+
+	// What Parse and Run do on a failure, see SetErrorHandling, and the
+	// last failure with its exit code.
+	errorHandling flag.ErrorHandling
+	err           error
+	errCode       int
+
+	// The exit code of wrong invocations, see SetUsageErrorCode, and
+	// of those without a sub-command, see SetNoCommandCode.
+	usageErrorCode     int
+	noCommandErrorCode int
+
+	// Prints the usage if there are no subcommands.
+	topLevelUsage func(w io.Writer)
+
+	// The format results of OutputCmds are printed in.
+	outputFormat string
+
+	// Resolves the writers a subcommand outputs to while it runs.
+	outputResolver func(cmdName string) (out, err io.Writer)
+
+	// The format the failures of Parse are reported in.
+	errorFormat string
+
+	// How the values of flags must be given.
+	flagValueStyle FlagValueStyle
+
+	// Rejects flags given after positional arguments.
+	flagsBeforeArgs bool
+
+	// The arguments asking for the usage, see SetHelpTokens.
+	helpTokens []string
+
+	// The usage of the -h flag, see SetHelpFlagUsage.
+	helpUsage string
+
+	// How much the usage shows, see SetHelpLevel and
+	// SetUsageVerbosity.
+	helpLevel      string
+	usageVerbosity int
+
+	// Drops the program name from the arguments of Parse.
+	skipProgramArg bool
+
+	// Renders the usage, see SetHelpRenderer.
+	helpRenderer HelpRenderer
+
+	// The environment variable holding the arguments if none are
+	// given, see SetArgsEnv.
+	argsEnv string
+
+	// The directory of the lock files, see SetLockDir.
+	lockDir string
+
+	// The aliases defined by the user, see LoadUserAliases.
+	userAliases map[string][]string
+
+	// The global flags holding secrets, see MarkFlagSensitive.
+	sensitiveFlags map[string]bool
+
+	// The build of the program, see SetVersion.
+	version *buildInfo
+
+	// Recorded by subcommands and flushed to metricsSink after they
+	// run.
+	metrics     *Metrics
+	metricsSink func(values map[string]float64)
+
+	// Registered by the running subcommand, see OnCleanup.
+	cleanups []func()
+
+	// The writer of the warnings, StdErr if nil.
+	warnOutput io.Writer
+
+	// Renders the path of a subcommand in its usage.
+	pathFormatter func(path []string) string
+
+	// Reports what would run instead of running it, see
+	// EnableCheckMode.
+	checkMode bool
+
+	// Passed to the running subcommand, see SetAppState.
+	appState interface{}
+
+	// Decides which subcommands are available, see SetAuthorizer.
+	authorizer func(cmdName string) bool
+
+	// The reader prompts and stdin helpers read from, StdInput if nil.
+	input io.Reader
+
+	// Buffers the lines read from readerSource, see Stdin.
+	reader       *bufio.Reader
+	readerSource io.Reader
 }
 
 func New(program string, flags *flag.FlagSet) *Commands {
-	return &Commands{program: program, flags: flags}
+	return &Commands{program: program, flags: flags, usageVerbosity: UsageFull, errorHandling: flag.ExitOnError}
+}
+
+// Returns a copy with the settings and a copy of the registered
+// sub-commands, but with its own empty global flags and none of the
+// parse or run state, so that each copy can be parsed independently,
+// e.g. in table driven tests. The commands are copied if they implement
+// Cloner, and shared otherwise, so they must not keep any state, e.g.
+// bind their flags to their fields, to be parsed concurrently.
+func (c *Commands) Clone() *Commands {
+	clone := *c
+	clone.flags = flag.NewFlagSet(c.program, flag.ContinueOnError)
+	clone.list = c.cloneList(&clone, nil)
+	clone.userAliases = make(map[string][]string, len(c.userAliases))
+	for name, args := range c.userAliases {
+		clone.userAliases[name] = append([]string(nil), args...)
+	}
+	clone.sensitiveFlags = copyBools(c.sensitiveFlags)
+	clone.matchingCmd = nil
+	clone.matchingTarget = nil
+	clone.matchingFlagSet = nil
+	clone.args = nil
+	clone.flagHelp = false
+	clone.guided = false
+	clone.flagAllowUnprivileged = false
+	clone.flagYes = false
+	clone.reader = nil
+	clone.readerSource = nil
+	clone.err = nil
+	clone.errCode = 0
+	clone.cleanups = nil
+	clone.metrics = nil
+	return &clone
 }
 
 type cmdInstance struct {
@@ -79,67 +266,446 @@ type cmdInstance struct {
 	description   string
 	command       Cmd
 	requiredFlags []string
+
+	// Prints the usage instead of running if the command is
+	// invoked without any arguments or flags.
+	helpOnEmpty bool
+
+	// Passes the arguments to the command untouched, without
+	// parsing any flags.
+	rawArgs bool
+
+	// The name of the subcommand this one forwards to, and the
+	// arguments put in front of the user given ones.
+	forward     string
+	forwardArgs []string
+
+	// The categories the command is listed under in the usage.
+	categories []string
+
+	// The placeholder of the arguments after "--" in the usage, which
+	// are forwarded verbatim by the command.
+	passthrough string
+
+	// The constraints between the flags of the command.
+	constraints []Constraint
+
+	// Example invocations of the command.
+	examples []string
+
+	// Free-form metadata for tooling, see Annotate.
+	annotations map[string]string
+
+	// The flags holding secrets, see MarkFlagSensitive.
+	sensitiveFlags map[string]bool
+
+	// The command must run with elevated privileges.
+	requireRoot bool
+
+	// The message shown when the deprecated command runs.
+	deprecated string
+
+	// The message shown when asking to confirm the destructive
+	// command.
+	destructive string
+
+	// The arguments passed to the command if none are given.
+	defaultArgs []string
+
+	// The values allowed for the positional arguments by index.
+	allowedArgs map[int][]string
+
+	// Holds a lock while running, see RequireLock.
+	requireLock bool
+
+	// Joins the arguments into one, shown as <joinArgs...> in the
+	// usage.
+	joinArgs string
+
+	// The other names of the command, see OnAlias.
+	aliases []string
+
+	// The group the command belongs to, and the sub-commands of the
+	// command if it is a group, see OnGroup.
+	parent *cmdInstance
+	group  *Commands
 }
 
-// Registers a Cmd for the provided sub-command name. E.g. name is the
-// `status` in `git status`.
-func (c *Commands) On(name, description string, command Cmd, requiredFlags []string) {
+// Returns the names leading to the command, starting from the
+// top-level sub-command.
+func (subcmd *cmdInstance) path() []string {
+	if subcmd.parent == nil {
+		return []string{subcmd.name}
+	}
+	return append(subcmd.parent.path(), subcmd.name)
+}
+
+// Returns the path of the command joined with spaces, e.g. "remote add".
+func (subcmd *cmdInstance) pathName() string {
+	return strings.Join(subcmd.path(), " ")
+}
+
+// Returns the sub-command named name or one of its aliases.
+func (c *Commands) lookup(name string) *cmdInstance {
 	for _, subcmd := range c.list {
 		if subcmd.name == name {
-			panic(errors.New("命令 '"+name+"' 已存在"))
+			return subcmd
+		}
+		for _, alias := range subcmd.aliases {
+			if alias == name {
+				return subcmd
+			}
 		}
 	}
+	return nil
+}
+
+func (c *Commands) mustLookup(name string) *cmdInstance {
+	subcmd := c.find(name)
+	if subcmd == nil {
+		panic(errors.New("命令 '" + name + "' 不存在"))
+	}
+	return subcmd
+}
+
+// Registers a Cmd for the provided sub-command name. E.g. name is the
+// `status` in `git status`.
+func (c *Commands) On(name, description string, command Cmd, requiredFlags []string) {
+	if c.lookup(name) != nil {
+		panic(errors.New("命令 '" + name + "' 已存在"))
+	}
 	c.list = append(c.list, &cmdInstance{
 		name:          name,
 		description:   description,
 		command:       command,
 		requiredFlags: requiredFlags,
+		parent:        c.parent,
 	})
 }
 
-// Prints the usage.
-func (c *Commands) Usage() {
-	if len(c.list) == 0 {
-		// no subcommands
-		ErrOutput("使用方法: %s [选项]", c.program)
-		c.flags.PrintDefaults()
-		return
+// Registers a Cmd like On, which can also be invoked by the aliases,
+// e.g. co for checkout. The usage lists it by its name followed by the
+// aliases. It panics if an alias is taken by another sub-command or
+// alias, like On does for a taken name.
+func (c *Commands) OnAlias(name string, aliases []string, description string, command Cmd, requiredFlags []string) {
+	for i, alias := range aliases {
+		if alias == name || c.lookup(alias) != nil {
+			panic(errors.New("命令 '" + alias + "' 已存在"))
+		}
+		for _, other := range aliases[:i] {
+			if alias == other {
+				panic(errors.New("命令 '" + alias + "' 已存在"))
+			}
+		}
 	}
+	c.On(name, description, command, requiredFlags)
+	c.list[len(c.list)-1].aliases = aliases
+}
 
-	ErrOutput("使用方法: %s [选项] 子命令 [选项] \n", c.program)
-	ErrOutput("子命令列表:")
-	for _, subcmd := range c.list {
-		ErrOutput("  %-15s %s", subcmd.name, subcmd.description)
+// Registers a sub-command which runs the target sub-command with args
+// put in front of the given arguments, e.g. `logs` running
+// `journal -follow`. It is listed with its own description, and its
+// help shows the flags of the target.
+func (c *Commands) OnForward(name, description, target string, args []string) {
+	c.On(name, description, nil, nil)
+	subcmd := c.list[len(c.list)-1]
+	subcmd.forward = target
+	subcmd.forwardArgs = args
+}
+
+// Follows the forwarding subcommands to the one which really runs,
+// prepending the forwarded arguments to args. The returned command is
+// nil if a target doesn't exist.
+func (c *Commands) resolve(subcmd *cmdInstance, args []string) (*cmdInstance, []string) {
+	if c.forwardCycle(subcmd) != nil {
+		return nil, args
 	}
+	for subcmd != nil && subcmd.forward != "" {
+		args = append(append([]string{}, subcmd.forwardArgs...), args...)
+		subcmd = c.owner(subcmd).lookup(subcmd.forward)
+	}
+	return subcmd, args
+}
 
-	// Returns the total number of globally registered flags.
-	count := 0
-	c.flags.VisitAll(func(flag *flag.Flag) {
-		count++
-	})
+// Returns the names of the sub-commands forwarding to each other in a
+// cycle reached from subcmd, ending with the first one repeated, e.g.
+// a, b, a. It returns nil if there is no cycle.
+func (c *Commands) forwardCycle(subcmd *cmdInstance) []string {
+	var names []string
+	seen := make(map[*cmdInstance]int)
+	for subcmd != nil && subcmd.forward != "" {
+		if i, ok := seen[subcmd]; ok {
+			return append(names[i:], subcmd.name)
+		}
+		seen[subcmd] = len(names)
+		names = append(names, subcmd.name)
+		subcmd = c.owner(subcmd).lookup(subcmd.forward)
+	}
+	return nil
+}
+
+// Marks the named sub-command to print its usage instead of running
+// when it is invoked without any arguments or flags.
+func (c *Commands) HelpOnEmptyArgs(name string) {
+	c.mustLookup(name).helpOnEmpty = true
+}
+
+// Sets the arguments the named sub-command runs with when it is invoked
+// without any, e.g. HEAD for a log command. Any given argument replaces
+// all of the defaults.
+func (c *Commands) SetDefaultArgs(name string, args []string) {
+	c.mustLookup(name).defaultArgs = args
+}
+
+// Makes the named sub-command take the rest of the line as a single
+// argument, e.g. a message, joining its arguments with spaces before
+// they are passed to Run. The usage shows them as <placeholder...>.
+func (c *Commands) SetJoinArgs(name, placeholder string) {
+	c.mustLookup(name).joinArgs = placeholder
+}
+
+// Attaches the annotation key with value to the named sub-command, e.g.
+// its stability or owner, for tools listing the sub-commands. It is
+// exposed by List and DumpJSON but not shown in the usage.
+func (c *Commands) Annotate(name string, key, value string) {
+	subcmd := c.mustLookup(name)
+	if subcmd.annotations == nil {
+		subcmd.annotations = make(map[string]string)
+	}
+	subcmd.annotations[key] = value
+}
+
+// Lists the named sub-command under each of the categories in the
+// usage, instead of among the uncategorized sub-commands.
+func (c *Commands) SetCategories(name string, categories ...string) {
+	subcmd := c.mustLookup(name)
+	subcmd.categories = nil
+	for _, category := range categories {
+		found := false
+		for _, existing := range subcmd.categories {
+			found = found || existing == category
+		}
+		if !found {
+			subcmd.categories = append(subcmd.categories, category)
+		}
+	}
+}
+
+// Declares that the named sub-command forwards the arguments after
+// "--" verbatim, the usage shows them with the placeholder, e.g.
+// `<command> [args...]`.
+func (c *Commands) SetPassthrough(name, placeholder string) {
+	if placeholder == "" {
+		placeholder = "[参数...]"
+	}
+	c.mustLookup(name).passthrough = placeholder
+}
+
+// Sets the function deriving the exit code from an error returned by a
+// sub-command, e.g. to follow the sysexits.h conventions. If it returns
+// 0, the code of an *Error is used, and -1 for other errors.
+func (c *Commands) SetExitCoder(coder func(err error) int) {
+	c.exitCoder = coder
+}
+
+// Sets what Parse and Run do on a failure once it's reported:
+// flag.ExitOnError exits, the default, flag.ContinueOnError returns and
+// leaves it to Err, and flag.PanicOnError panics with it. Nothing runs
+// after Parse fails.
+func (c *Commands) SetErrorHandling(mode flag.ErrorHandling) {
+	c.errorHandling = mode
+}
+
+// Returns the failure of the last Parse or Run, and the code to exit
+// with, or nil and 0 if they succeeded.
+func (c *Commands) Err() (error, int) {
+	return c.err, c.errCode
+}
+
+// Records the failure of Parse or Run, and exits or panics as set by
+// SetErrorHandling.
+func (c *Commands) fail(code int, err error) {
+	c.err, c.errCode = err, code
+	switch c.errorHandling {
+	case flag.ContinueOnError:
+	case flag.PanicOnError:
+		panic(err)
+	default:
+		Exit(code)
+	}
+}
+
+// Sets whether Parse drops the first of its arguments as the name of
+// the program. By default Parse expects the arguments to start with
+// the sub-command name, as returned by flag.Args(); set it to pass
+// os.Args as is when there are no global flags. Don't set it on
+// Default, the package-level Parse already drops the program name.
+func (c *Commands) SetSkipProgramArg(b bool) {
+	c.skipProgramArg = b
+}
+
+// Sets the environment variable Parse reads the whole invocation from,
+// e.g. "deploy -env prod", when it is given no arguments, for launchers
+// which can set the environment but not the arguments. The value is
+// split by SplitArgs. Arguments given to Parse always take precedence.
+func (c *Commands) SetArgsEnv(envVar string) {
+	c.argsEnv = envVar
+}
+
+// Sets the exit code used when Parse fails because of a wrong
+// invocation, e.g. an unknown sub-command, invalid or missing flags or
+// violated constraints, to e.g. 64 (EX_USAGE of sysexits.h) instead of
+// 1, or 2 for invalid flags like the flag package, so that scripts can
+// tell a wrong invocation from a failure of the sub-command.
+func (c *Commands) SetUsageErrorCode(code int) {
+	c.usageErrorCode = code
+}
+
+// Returns the exit code set by SetUsageErrorCode, or 1.
+func (c *Commands) usageCode() int {
+	if c.usageErrorCode != 0 {
+		return c.usageErrorCode
+	}
+	return 1
+}
+
+// Returns the exit code set by SetUsageErrorCode, or 2 for the invalid
+// flags.
+func (c *Commands) flagErrorCode() int {
+	if c.usageErrorCode != 0 {
+		return c.usageErrorCode
+	}
+	return 2
+}
+
+// Sets the exit code used when Parse fails because no sub-command is
+// given and there is no default one, so that scripts can tell it from
+// other failures. The failure matches ErrNoSubcommand, see ParseErr.
+func (c *Commands) SetNoCommandCode(code int) {
+	c.noCommandErrorCode = code
+}
+
+// Returns the exit code set by SetNoCommandCode, or 1.
+func (c *Commands) noCommandCode() int {
+	if c.noCommandErrorCode != 0 {
+		return c.noCommandErrorCode
+	}
+	return 1
+}
+
+// Sets the function resolving the writers the named sub-command outputs
+// to, e.g. to send a noisy command to a log file. StdOutput and StdErr
+// are replaced by the returned writers while the sub-command runs,
+// unless they are nil.
+func (c *Commands) SetOutputResolver(resolver func(cmdName string) (out, err io.Writer)) {
+	c.outputResolver = resolver
+}
+
+// Sets the sub-command to run if none is given.
+func (c *Commands) SetDefaultCommand(name string) {
+	c.defaultName = name
+}
+
+// Runs the first registered sub-command if none is given, the one set
+// by SetDefaultCommand takes precedence.
+func (c *Commands) SetDefaultToFirst(b bool) {
+	c.defaultToFirst = b
+}
+
+// Runs the named sub-command, e.g. setup, if none is given and detect
+// reports that the program runs for the first time, e.g. because its
+// configuration directory doesn't exist. It takes precedence over the
+// default sub-command, and once detect reports false nothing changes.
+func (c *Commands) SetFirstRunCommand(name string, detect func() bool) {
+	c.firstRunName = name
+	c.firstRunDetect = detect
+}
 
-	if count > 0 {
-		ErrOutput("\n选项:")
-		c.flags.PrintDefaults()
+// Returns the name of the sub-command to run if none is given.
+func (c *Commands) defaultCommand() string {
+	if c.firstRunName != "" && c.firstRunDetect != nil && c.firstRunDetect() {
+		return c.firstRunName
+	}
+	if c.defaultName != "" {
+		return c.defaultName
+	}
+	if c.defaultToFirst && len(c.list) > 0 {
+		return c.list[0].name
 	}
-	ErrOutput("\n查看子命令的帮助: %s 子命令 -h", c.program)
+	return ""
+}
+
+// Enables or disables the hint pointing to the usage which is
+// printed when an unknown sub-command is given and the usage is
+// suppressed, see SetSuppressUsageOnError. It is enabled by default.
+func (c *Commands) SetUsageHint(enabled bool) {
+	c.noUsageHint = !enabled
+}
+
+// Keeps Parse and Run from printing the usage after a failure, only
+// the message is printed, e.g. for scripts parsing the errors. The
+// usage asked for with -h is still printed.
+func (c *Commands) SetSuppressUsageOnError(b bool) {
+	c.suppressUsage = b
+}
+
+// Sets how the path of a sub-command, e.g. `db migrate`, is rendered in
+// its usage. By default it is prefixed with the program name and
+// joined with spaces.
+func (c *Commands) SetUsagePathFormatter(formatter func(path []string) string) {
+	c.pathFormatter = formatter
+}
+
+func (c *Commands) usagePath(subcmd *cmdInstance) string {
+	if c.pathFormatter != nil {
+		return c.pathFormatter(subcmd.path())
+	}
+	return c.program + " " + strings.Join(subcmd.path(), " ")
+}
+
+// Sets the function printing the usage of a program without any
+// sub-commands, e.g. with a description and examples, in place of the
+// plain list of the global flags.
+func (c *Commands) SetTopLevelUsage(usage func(w io.Writer)) {
+	c.topLevelUsage = usage
+}
+
+// Prints the usage.
+func (c *Commands) Usage() {
+	c.renderer().RenderUsage(c, StdErr)
 }
 
 func (c *Commands) SubcommandUsage(subcmd *cmdInstance) {
+	c.subcommandUsage(subcmd, StdErr)
+}
+
+// Prints the usage of subcmd to w, unless it prints its usage itself.
+func (c *Commands) subcommandUsage(subcmd *cmdInstance, w io.Writer) {
 	if u, ok := subcmd.command.(interface{ Usage() }); ok {
 		u.Usage()
 		return
 	}
+	c.renderer().RenderSubcommandUsage(c, c.cmdInfo(subcmd, true), w)
+}
+
+// Prints the usage of the named sub-command, or the top-level usage if
+// name is empty, to StdOutput rather than StdErr, for help the user
+// asked for; a sub-command with its own Usage method still prints its
+// usage itself. It returns an error if there is no such sub-command.
+func (c *Commands) ShowHelp(name string) error {
+	var subcmd *cmdInstance
+	if name != "" {
+		if subcmd = c.find(name); subcmd == nil {
+			return errors.New("命令 '" + name + "' 不存在")
+		}
+	}
 
-	ErrOutput("%s", subcmd.description)
-	// should only output sub command flags, ignore h flag.
-	fs := subcmd.command.Flags(flag.NewFlagSet(subcmd.name, flag.ContinueOnError))
-	flagCount := 0
-	fs.VisitAll(func(flag *flag.Flag) { flagCount++ })
-	if flagCount > 0 {
-		ErrOutput("使用方法: %s %s [选项]", c.program, subcmd.name)
-		fs.PrintDefaults()
+	if subcmd != nil {
+		c.subcommandUsage(subcmd, StdOutput)
+	} else {
+		c.renderer().RenderUsage(c, StdOutput)
 	}
+	return nil
 }
 
 // Parses the flags and leftover arguments to match them with a
@@ -150,84 +716,406 @@ func (c *Commands) SubcommandUsage(subcmd *cmdInstance) {
 // don't match the configuration.
 // Global flags are accessible once Parse executes.
 func (c *Commands) Parse(args []string) {
+	c.err, c.errCode = nil, 0
+	if c.checkMode {
+		c.matchingCmd, c.matchingTarget, c.matchingFlagSet, c.args = nil, nil, nil, nil
+		c.reportCheck(c.parse(args))
+		// nothing runs in check mode
+		c.matchingCmd = nil
+		if c.errorHandling != flag.ContinueOnError {
+			Exit(0)
+		}
+		return
+	}
+	if err := c.parse(args); err != nil {
+		c.reportParseError(err)
+		c.fail(err.code, err.asError())
+	}
+}
+
+// Parses the arguments in line separated by sep, e.g. a tab, the way
+// Parse does, but returns the failure instead of exiting. Unlike
+// SplitArgs, no quoting is recognized, which suits inputs from a
+// controlled source such as a protocol.
+func (c *Commands) ParseString(line, sep string) error {
+	if sep == "" {
+		return errors.New("分隔符不能为空")
+	}
+	var args []string
+	if line != "" {
+		args = strings.Split(line, sep)
+	}
+	if e := c.parse(args); e != nil {
+		c.reportParseError(e)
+		return e.asError()
+	}
+	return nil
+}
+
+// Parses args the way Parse does, but returns the failure instead of
+// reporting it and exiting, e.g. to test the parsing. It matches
+// ErrNoSubcommand or ErrUnknownSubcommand with errors.Is if no
+// sub-command or an unknown one is given, and is a *CommandError
+// carrying the name of the sub-command unless several validations
+// failed at once, see ValidationError.
+func (c *Commands) ParseErr(args []string) error {
+	if e := c.parse(args); e != nil {
+		return e.asError()
+	}
+	return nil
+}
+
+// ParseOptions are the settings of a single call to ParseWith.
+type ParseOptions struct {
+	// Where the failures and the usage are written while parsing,
+	// StdErr if nil.
+	UsageOutput io.Writer
+	// What to do on a failure once it's reported: flag.ContinueOnError
+	// returns it, flag.ExitOnError exits like Parse does, and
+	// flag.PanicOnError panics with it.
+	ErrorHandling flag.ErrorHandling
+	// The sub-command to run if none is given, overriding the one set
+	// by SetDefaultCommand.
+	DefaultCommand string
+}
+
+// Parses args the way Parse does, with the settings in opts applying
+// to this call only.
+func (c *Commands) ParseWith(args []string, opts ParseOptions) error {
+	if opts.UsageOutput != nil {
+		old := StdErr
+		StdErr = opts.UsageOutput
+		defer func() { StdErr = old }()
+	}
+	if opts.DefaultCommand != "" {
+		old := c.defaultName
+		c.defaultName = opts.DefaultCommand
+		defer func() { c.defaultName = old }()
+	}
+	e := c.parse(args)
+	if e == nil {
+		return nil
+	}
+	c.reportParseError(e)
+	err := e.asError()
+	switch opts.ErrorHandling {
+	case flag.ExitOnError:
+		Exit(e.code)
+	case flag.PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// Returns the failure of an unknown sub-command name of the group
+// parent, or of c if parent is nil, suggesting the close ones among
+// its sub-commands, followed by the usage of the group.
+func (c *Commands) unknownCommand(name string, parent *cmdInstance) *parseError {
+	candidates := c.list
+	if parent != nil {
+		candidates = parent.group.list
+	}
+	msg := fmt.Sprintf("未知的子命令: %q", name)
+	if names := c.suggest(name, candidates); len(names) > 0 {
+		msg += ", 您是不是要找: " + strings.Join(names, ", ")
+	}
+	return &parseError{
+		problems: []problem{{Kind: KindUnknownCommand, Command: name, Message: msg}},
+		usage:    true,
+		subcmd:   parent,
+		hint:     true,
+		code:     c.usageCode(),
+	}
+}
+
+// Does the work of Parse, returning the failure instead of reporting
+// it and exiting. Nothing is left to run after a failure.
+func (c *Commands) parse(args []string) *parseError {
+	c.matchingCmd, c.matchingTarget, c.args = nil, nil, nil
+	e := c.match(args)
+	if e != nil {
+		c.matchingCmd, c.matchingTarget, c.args = nil, nil, nil
+	}
+	return e
+}
+
+// Matches args with a sub-command and parses its flags, see parse.
+func (c *Commands) match(args []string) *parseError {
+	if c.skipProgramArg && len(args) > 0 {
+		args = args[1:]
+	}
 	// if there are no subcommands registered,
 	// return immediately
 	if len(c.list) < 1 {
-		return
+		return nil
 	}
 
+	c.guided = false
+	c.flagHelp = false
+	if len(args) < 1 && c.argsEnv != "" {
+		if value := os.Getenv(c.argsEnv); value != "" {
+			envArgs, err := SplitArgs(value)
+			if err != nil {
+				return &parseError{
+					problems: []problem{{Kind: KindArgsEnv, Message: fmt.Sprintf("环境变量 %s 无效: %s", c.argsEnv, err)}},
+					code:     c.usageCode(),
+				}
+			}
+			args = envArgs
+		}
+	}
 	if len(args) < 1 {
-		c.Usage()
-		os.Exit(1)
-		return
+		name := c.defaultCommand()
+		if name == "" && c.interactiveFallback && !c.checkMode && isTerminal(c.inputSource()) {
+			name = c.chooseCommand()
+			c.guided = true
+		}
+		if name == "" {
+			return &parseError{
+				problems: []problem{{Kind: KindNoCommand, Message: "需要指定子命令"}},
+				usage:    true,
+				code:     c.noCommandCode(),
+			}
+		}
+		args = []string{name}
 	}
-	
-	name := args[0]
-	var subcmd *cmdInstance
-	for _, sub := range c.list {
-		if sub.name == name {
-			subcmd = sub
-			break
+
+	expanded, err := c.expandAliases(args)
+	if err != nil {
+		return &parseError{
+			problems: []problem{{Kind: KindAliasLoop, Command: args[0], Message: err.Error()}},
+			code:     c.usageCode(),
 		}
 	}
+	args = expanded
+	name := args[0]
+	subcmd := c.lookup(name)
 	if subcmd == nil {
-		c.Usage()
-		os.Exit(1)
+		return c.unknownCommand(name, nil)
+	}
+	// descend into the groups, e.g. remote add
+	for subcmd.group != nil {
+		if !c.authorized(subcmd) {
+			return subcmd.failure(KindNotAvailable, nil, fmt.Sprintf("命令 '%s' 不可用", subcmd.name), ExitNoPermission, false)
+		}
+		args = args[1:]
+		if len(args) > 0 && isHelpFlag(args[0]) {
+			c.matchingCmd, c.matchingTarget, c.args = subcmd, subcmd, nil
+			c.flagHelp = true
+			return nil
+		}
+		if len(args) == 0 {
+			return subcmd.failure(KindNoCommand, nil, "需要指定子命令", c.noCommandCode(), true)
+		}
+		name = args[0]
+		next := subcmd.group.lookup(name)
+		if next == nil {
+			return c.unknownCommand(name, subcmd)
+		}
+		subcmd = next
+	}
+
+	c.matchingCmd = subcmd
+	// the forwarded arguments are checked by Validate, not on every run
+	userArgs := args[1:]
+	target, args := c.resolve(subcmd, userArgs)
+	if target == nil {
+		if cycle := c.forwardCycle(subcmd); cycle != nil {
+			return subcmd.failure(KindUnknownCommand, nil, "快捷命令存在循环: "+strings.Join(cycle, " -> "), c.usageCode(), false)
+		}
+		return subcmd.failure(KindUnknownCommand, nil, fmt.Sprintf("命令 '%s' 转发的目标命令不存在", name), c.usageCode(), false)
+	}
+	if !c.authorized(subcmd) || !c.authorized(target) {
+		return subcmd.failure(KindNotAvailable, nil, fmt.Sprintf("命令 '%s' 不可用", name), ExitNoPermission, false)
+	}
+	c.matchingTarget = target
+	if target.rawArgs {
+		c.args = args
+		return nil
 	}
 
-	fs := flag.NewFlagSet(name, flag.ExitOnError)
-	fs = subcmd.command.Flags(fs)
-	fs.BoolVar(&c.flagHelp, "h", false, "")
-	fs.BoolVar(&c.flagHelp, "?", false, "")
-	fs.BoolVar(&c.flagHelp, "help", false, "")
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs = target.command.Flags(fs)
+	c.helpFlags(fs, &c.flagHelp)
 	// fs.BoolVar(&c.flagHelp, "-help", false, "")
+	c.stdinArgsFlags(fs)
+	c.privilegeFlags(subcmd, fs)
+	c.confirmFlags(subcmd, fs)
 
-	c.matchingCmd = subcmd
-	fs.Usage = func() {
-		c.SubcommandUsage(subcmd)
+	// errors are reported by Parse
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	if err := c.checkFlagStyle(fs, userArgs); err != nil {
+		return subcmd.failure(KindInvalidFlag, nil, err.Error(), c.flagErrorCode(), true)
+	}
+	if err := c.checkFlagOrder(fs, userArgs); err != nil {
+		return subcmd.failure(KindInvalidFlag, nil, err.Error(), c.flagErrorCode(), true)
+	}
+	if err := fs.Parse(args); err != nil {
+		return subcmd.failure(KindInvalidFlag, nil, err.Error(), c.flagErrorCode(), true)
 	}
-	fs.Parse(args[1:])
+	c.matchingFlagSet = fs
+	c.warnDeprecatedFlags(fs)
 	c.args = fs.Args()
+	if subcmd.helpOnEmpty && len(c.args) == 0 && fs.NFlag() == 0 {
+		c.flagHelp = true
+		return nil
+	}
+	if c.flagHelp {
+		// the usage is asked for, the flags needn't be valid
+		return nil
+	}
+	if len(c.args) == 1 && c.isHelpToken(c.args[0]) {
+		c.flagHelp = true
+		c.args = nil
+		return nil
+	}
 
-	// Check for required flags.
-	flagMap := make(map[string]bool)
-	for _, flagName := range subcmd.requiredFlags {
-		flagMap[flagName] = true
+	// Check for required flags and constraints, reporting all of the
+	// violations at once.
+	var e *parseError
+	missing := missingFlags(fs, target.requiredFlags)
+	if len(missing) > 0 {
+		missing = c.promptFlags(fs, missing)
 	}
-	fs.Visit(func(f *flag.Flag) {
-		delete(flagMap, f.Name)
-	})
-	if len(flagMap) > 0 {
-		c.SubcommandUsage(c.matchingCmd)
-		os.Exit(1)
+	if len(missing) > 0 {
+		e = subcmd.failure(KindMissingRequiredFlag, missing, "缺少必需的选项: "+joinFlags(missing), c.usageCode(), true)
+	}
+	if errs := checkConstraints(fs, target.constraints); len(errs) > 0 {
+		if e == nil {
+			e = &parseError{subcmd: subcmd, usage: true, code: c.usageCode()}
+		}
+		for _, err := range errs {
+			e.problems = append(e.problems, problem{
+				Kind:    KindFlagConstraint,
+				Command: subcmd.name,
+				Flags:   err.(*constraintError).Flags,
+				Message: err.Error(),
+			})
+		}
+	}
+	if e != nil {
+		return e
+	}
+
+	if !c.checkMode {
+		stdinArgs, err := c.readStdinArgs(fs)
+		if err != nil {
+			return subcmd.failure(KindStdin, nil, "读取标准输入失败: "+err.Error(), 1, false)
+		}
+		c.args = append(c.args, stdinArgs...)
+	}
+	if len(c.args) == 0 {
+		c.args = append([]string(nil), target.defaultArgs...)
+	}
+	if target.joinArgs != "" && len(c.args) > 0 {
+		c.args = []string{strings.Join(c.args, " ")}
 	}
+	if err := checkArgValues(c.args, target.allowedArgs); err != nil {
+		return subcmd.failure(KindInvalidArg, nil, err.Error(), c.usageCode(), true)
+	}
+	return nil
+}
+
+// Returns the flags in required which aren't set in fs.
+func missingFlags(fs *flag.FlagSet, required []string) []string {
+	set := setFlags(fs)
+	var missing []string
+	for _, name := range required {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
 }
 
 // Runs the subcommand's runnable. If there is no subcommand
-// registered, it silently returns.
+// registered, it silently returns. So does a subcommand failing with
+// EPIPE because the reader of its output went away.
 func (c *Commands) Run() {
-	if c.matchingCmd != nil {
-		if c.flagHelp {
-			c.SubcommandUsage(c.matchingCmd)
-			return
+	if code, err := c.run(); err != nil {
+		c.fail(code, err)
+	}
+}
+
+// Does the work of Run, returning the failure and the code to exit
+// with instead of exiting. The failure is already reported.
+func (c *Commands) run() (int, error) {
+	if c.matchingCmd == nil {
+		return 0, nil
+	}
+	if !c.authorized(c.matchingCmd) || !c.authorized(c.matchingTarget) {
+		msg := fmt.Sprintf("命令 '%s' 不可用", c.matchingCmd.name)
+		ErrOutput("%s", msg)
+		return ExitNoPermission, &Error{Code: ExitNoPermission, Message: msg}
+	}
+	if c.flagHelp {
+		c.SubcommandUsage(c.matchingCmd)
+		return 0, nil
+	}
+	if !c.checkPrivilege(c.matchingCmd) {
+		return ExitNoPermission, &Error{Code: ExitNoPermission, Message: "需要管理员权限"}
+	}
+	if !c.confirm(c.matchingCmd) {
+		return 1, &Error{Code: 1, Message: "已取消"}
+	}
+	unlock, err := c.lock(c.matchingTarget)
+	if err != nil {
+		ErrOutput("%s", err)
+		return 1, err
+	}
+	defer unlock()
+	defer c.trapExit()()
+	if c.matchingCmd.deprecated != "" {
+		c.warn("命令 '%s' 已废弃, %s", c.matchingCmd.name, c.matchingCmd.deprecated)
+	}
+
+	if c.outputResolver != nil {
+		out, errOut := c.outputResolver(c.matchingCmd.name)
+		oldOut, oldErr := StdOutput, StdErr
+		if out != nil {
+			StdOutput = out
 		}
+		if errOut != nil {
+			StdErr = errOut
+		}
+		defer func() {
+			StdOutput, StdErr = oldOut, oldErr
+		}()
+	}
 
-		if err := c.matchingCmd.command.Run(c.args); err != nil {
-			var code = -1
-			var help = false
-			if e, ok := err.(*Error); ok {
-				code = e.Code
-				help = e.Help
+	setState(c.matchingTarget.command, c.appState)
+	err = c.consumeStdin(c.matchingTarget.command)
+	if err == nil {
+		err = c.matchingTarget.command.Run(c.args)
+	}
+	c.flushMetrics()
+	if isBrokenPipe(err) {
+		// the reader went away, e.g. tool completion bash | head
+		return 0, nil
+	}
+	if err != nil {
+		var code = -1
+		var help = false
+		if e, ok := err.(*Error); ok {
+			code = e.Code
+			help = e.Help
+		}
+		if c.exitCoder != nil {
+			if exitCode := c.exitCoder(err); exitCode != 0 {
+				code = exitCode
 			}
+		}
 
-			ErrOutput("FATAL: %s", err.Error())
-			if help {
-				c.SubcommandUsage(c.matchingCmd)
-			}
-			os.Exit(code)
-			return
+		if msg := err.Error(); msg != "" {
+			ErrOutput("FATAL: %s: %s", strings.Join(c.matchingCmd.path(), " "), msg)
+		}
+		if help && !c.suppressUsage {
+			c.SubcommandUsage(c.matchingCmd)
 		}
+		return code, err
 	}
+	return 0, nil
 }
 
 // Parses flags and run's matching subcommand's runnable.
@@ -236,6 +1124,31 @@ func (c *Commands) ParseAndRun(args []string) {
 	c.Run()
 }
 
+// Parses and runs the named sub-command with args, capturing what it
+// writes to StdOutput and StdErr, e.g. to test a sub-command. It returns
+// the failure of parsing or the error of the sub-command instead of
+// exiting. StdOutput and StdErr are swapped while it runs, so it must
+// not be called concurrently.
+func (c *Commands) RunCaptured(name string, args []string) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+	oldOut, oldErr := StdOutput, StdErr
+	StdOutput, StdErr = &outBuf, &errBuf
+	defer func() {
+		StdOutput, StdErr = oldOut, oldErr
+	}()
+
+	args = append([]string{name}, args...)
+	if c.skipProgramArg {
+		args = append([]string{c.program}, args...)
+	}
+	if e := c.parse(args); e != nil {
+		c.reportParseError(e)
+		return outBuf.String(), errBuf.String(), e.asError()
+	}
+	_, err = c.run()
+	return outBuf.String(), errBuf.String(), err
+}
+
 var Default = New(os.Args[0], flag.CommandLine)
 
 func On(name, description string, command Cmd, requiredFlags []string) {
@@ -249,17 +1162,28 @@ func Usage() {
 var DefaultCommandName string
 
 
+var defaultParsePreHook func(c *Commands)
 var defaultParsePostHook func()
 
+// Sets a hook called with Default at the start of Parse, before the
+// global flags are parsed, so that sub-commands can be registered or
+// adjusted depending on the environment.
+func SetDefaultParsePreHook(hook func(c *Commands)) {
+	defaultParsePreHook = hook
+}
+
 func SetDefaultParsePostHook(hook func()) {
 	defaultParsePostHook = hook
 }
 
 func Parse() {
+	if defaultParsePreHook != nil {
+		defaultParsePreHook(Default)
+	}
 	flag.Usage = Default.Usage
 	flag.Parse()
 	args := flag.Args()
-	if len(args) == 0 {
+	if len(args) == 0 && DefaultCommandName != "" {
 		args = []string{DefaultCommandName}
 	}
 	if defaultParsePostHook != nil {
@@ -15,8 +15,14 @@
 package command
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -141,11 +147,811 @@ func TestAdditionalCommandArgs(t *testing.T) {
 	}
 }
 
-// Resets os.Args and the default flag set.
+// Tests if a command marked with HelpOnEmptyArgs prints its usage
+// instead of running when it is invoked without arguments.
+func TestHelpOnEmptyArgs(t *testing.T) {
+	resetForTesting("command1")
+	stderr := captureStdErr(t)
+
+	c1 := &testCmd1{}
+	On("command1", "description of command1", c1, []string{})
+	Default.HelpOnEmptyArgs("command1")
+	Parse()
+	Run()
+	if c1.run {
+		t.Error("command 'command1' was not expected to run, but it did")
+	}
+	if !strings.Contains(stderr.String(), "description of command1") {
+		t.Errorf("usage of command1 is expected, found %q", stderr.String())
+	}
+
+	resetForTesting("command1", "somearg")
+	c1 = &testCmd1{}
+	On("command1", "description of command1", c1, []string{})
+	Default.HelpOnEmptyArgs("command1")
+	Parse()
+	Run()
+	if !c1.run {
+		t.Error("command 'command1' was expected to run, but it didn't")
+	}
+}
+
+// Tests if the default arguments are used only without arguments.
+func TestDefaultArgs(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("log", "", &testCmd1{}, []string{})
+	c.SetDefaultArgs("log", []string{"HEAD"})
+	c.OnForward("l", "", "log", nil)
+
+	for _, test := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"log"}, []string{"HEAD"}},
+		{[]string{"log", "-flag1"}, []string{"HEAD"}},
+		{[]string{"l"}, []string{"HEAD"}},
+		{[]string{"log", "v1..v2"}, []string{"v1..v2"}},
+		{[]string{"log", "a", "b"}, []string{"a", "b"}},
+	} {
+		if err := c.parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.args, test.expected) {
+			t.Errorf("%v: expected the arguments %v, found %v", test.args, test.expected, c.args)
+		}
+	}
+}
+
+// Tests if the program name is dropped only if configured.
+func TestSkipProgramArg(t *testing.T) {
+	captureStdErr(t)
+	captureExit(t)
+	for _, skip := range []bool{false, true} {
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c1 := &testCmd1{}
+		c.On("command1", "", c1, []string{})
+		c.SetSkipProgramArg(skip)
+		args := []string{"command1", "arg"}
+		if skip {
+			args = []string{"/usr/bin/cmd", "command1", "arg"}
+		}
+		c.ParseAndRun(args)
+		if !c1.run || !reflect.DeepEqual(c.args, []string{"arg"}) {
+			t.Errorf("skip=%v: expected command1 to run with [arg], found run %v with %v", skip, c1.run, c.args)
+		}
+	}
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.SetSkipProgramArg(true)
+	if err := c.parse([]string{"command1"}); err == nil || err.problems[0].Kind != KindNoCommand {
+		t.Errorf("only the program name is expected to be no command, found %v", err)
+	}
+}
+
+// Tests if the arguments are joined into one and shown in the usage.
+func TestJoinArgs(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("commit", "", &testCmd1{}, []string{})
+	c.SetJoinArgs("commit", "message")
+	for _, test := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"commit", "-flag1", "fix", "the", "bug"}, []string{"fix the bug"}},
+		{[]string{"commit", "one"}, []string{"one"}},
+		{[]string{"commit"}, nil},
+	} {
+		if err := c.parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.args, test.expected) {
+			t.Errorf("%v: expected %q, found %q", test.args, test.expected, c.args)
+		}
+	}
+
+	c.SubcommandUsage(c.lookup("commit"))
+	if !strings.Contains(stderr.String(), "使用方法: cmd commit [选项] <message...>\n") {
+		t.Errorf("expected the joined arguments in the usage, found %q", stderr.String())
+	}
+}
+
+// Tests if the arguments are read from the environment only if none
+// are given.
+func TestArgsEnv(t *testing.T) {
+	captureStdErr(t)
+	captureExit(t)
+	t.Setenv("CMD_ARGS", `command1 -flag1 "a b"`)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+	c.SetArgsEnv("CMD_ARGS")
+	c.ParseAndRun(nil)
+	if !c1.run || !*c1.flag1 || !reflect.DeepEqual(c.args, []string{"a b"}) {
+		t.Errorf("expected command1 to run with -flag1 and [a b], found run %v with %v", c1.run, c.args)
+	}
+
+	if err := c.parse([]string{"command2"}); err != nil || c.matchingCmd.name != "command2" {
+		t.Errorf("the given arguments are expected to take precedence, found %v", err)
+	}
+
+	t.Setenv("CMD_ARGS", `command1 "a`)
+	if err := c.parse(nil); err == nil || err.problems[0].Kind != KindArgsEnv {
+		t.Errorf("an invalid environment variable is expected to fail, found %v", err)
+	}
+}
+
+// Tests if a line is split by the separator and parsed without exiting.
+func TestParseString(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("login", "", &testStringCmd{}, []string{})
+
+	if err := c.ParseString("login\t-token\ta b\targ", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	if c.matchingCmd == nil || c.matchingCmd.name != "login" {
+		t.Fatalf("expected login to match, found %v", c.matchingCmd)
+	}
+	if value := c.matchingFlagSet.Lookup("token").Value.String(); value != "a b" {
+		t.Errorf("expected the token 'a b', found %q", value)
+	}
+	if !reflect.DeepEqual(c.args, []string{"arg"}) {
+		t.Errorf("expected the argument 'arg', found %q", c.args)
+	}
+
+	if err := c.ParseString("unknown", "\t"); err == nil {
+		t.Error("an unknown command is expected to fail")
+	}
+	if err := c.ParseString("login", ""); err == nil {
+		t.Error("an empty separator is expected to fail")
+	}
+	if *code != -100 {
+		t.Errorf("no exit is expected, found %d", *code)
+	}
+}
+
+// Tests if the setup command runs only on the first run without a
+// command.
+func TestFirstRunCommand(t *testing.T) {
+	captureStdErr(t)
+	captureExit(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("setup", "", &testCmd1{}, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+	c.SetDefaultCommand("command2")
+	configured := false
+	c.SetFirstRunCommand("setup", func() bool { return !configured })
+
+	for _, test := range []struct {
+		configured bool
+		args       []string
+		expected   string
+	}{
+		{false, nil, "setup"},
+		{false, []string{"command2"}, "command2"},
+		{true, nil, "command2"},
+	} {
+		configured = test.configured
+		c.matchingCmd = nil
+		c.Parse(test.args)
+		if c.matchingCmd == nil || c.matchingCmd.name != test.expected {
+			t.Errorf("configured %v, %v: expected %s to match, found %v", test.configured, test.args, test.expected, c.matchingCmd)
+		}
+	}
+}
+
+// Tests if the options of ParseWith apply to the call only.
+func TestParseWith(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+	c.SetDefaultCommand("command1")
+
+	if err := c.ParseWith(nil, ParseOptions{DefaultCommand: "command2"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.matchingCmd.name != "command2" {
+		t.Errorf("expected command2 to match, found %s", c.matchingCmd.name)
+	}
+	c.ParseWith(nil, ParseOptions{})
+	if c.matchingCmd.name != "command1" {
+		t.Errorf("expected the default command to be restored, found %s", c.matchingCmd.name)
+	}
+
+	var usage bytes.Buffer
+	err := c.ParseWith([]string{"unknown"}, ParseOptions{UsageOutput: &usage})
+	if err == nil || !strings.Contains(usage.String(), "unknown") {
+		t.Errorf("expected the failure to be written to the usage output, found %q %v", usage.String(), err)
+	}
+	if stderr.String() != "" || *code != -100 {
+		t.Errorf("expected neither output to StdErr nor exit, found %q %d", stderr.String(), *code)
+	}
+
+	c.ParseWith([]string{"unknown"}, ParseOptions{ErrorHandling: flag.ExitOnError})
+	if *code != c.usageCode() || stderr.String() == "" {
+		t.Errorf("expected to report and exit with %d, found %q %d", c.usageCode(), stderr.String(), *code)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected to panic")
+		}
+	}()
+	c.ParseWith([]string{"unknown"}, ParseOptions{ErrorHandling: flag.PanicOnError})
+}
+
+// Tests if the failures are recorded, returned, or panicked with as
+// set by SetErrorHandling.
+func TestErrorHandling(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStringCmd{}
+	c.On("login", "", cmd, []string{"token"})
+	errFail := &Error{Code: 3, Message: "boom"}
+	c.On("fail", "", &testErrCmd{err: errFail}, []string{})
+	c.SetErrorHandling(flag.ContinueOnError)
+
+	c.ParseAndRun([]string{"login"})
+	if err, errCode := c.Err(); err == nil || errCode != c.usageCode() {
+		t.Errorf("expected the missing token to be recorded, found %v %d", err, errCode)
+	}
+	if c.matchingCmd != nil {
+		t.Error("nothing is expected to run after a failure")
+	}
+	c.ParseAndRun([]string{"fail"})
+	if err, errCode := c.Err(); err != errFail || errCode != 3 {
+		t.Errorf("expected the error of the command, found %v %d", err, errCode)
+	}
+	c.ParseAndRun([]string{"login", "-token", "a"})
+	if err, errCode := c.Err(); err != nil || errCode != 0 {
+		t.Errorf("expected no failure, found %v %d", err, errCode)
+	}
+	if *code != -100 {
+		t.Errorf("no exit is expected, found %d", *code)
+	}
+
+	c.SetErrorHandling(flag.ExitOnError)
+	c.ParseAndRun([]string{"fail"})
+	if *code != 3 {
+		t.Errorf("expected exit code 3, found %d", *code)
+	}
+
+	c.SetErrorHandling(flag.PanicOnError)
+	defer func() {
+		if r := recover(); r != errFail {
+			t.Errorf("expected to panic with the error, found %v", r)
+		}
+	}()
+	c.ParseAndRun([]string{"fail"})
+}
+
+// Tests if a command is matched by its aliases and listed with them.
+func TestOnAlias(t *testing.T) {
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.OnAlias("checkout", []string{"co", "switch"}, "description of checkout", &testCmd1{}, []string{})
+	c.On("remove", "description of remove", &testCmd2{}, []string{})
+
+	for _, name := range []string{"checkout", "co", "switch"} {
+		if err := c.ParseErr([]string{name, "-flag1"}); err != nil || c.matchingCmd.name != "checkout" {
+			t.Errorf("%s: expected checkout to match, found %v", name, err)
+		}
+	}
+	c.Usage()
+	if !strings.Contains(stderr.String(), "  checkout, co, switch description of checkout\n") {
+		t.Errorf("expected checkout to be listed with its aliases, found %q", stderr.String())
+	}
+	stderr.Reset()
+	c.SubcommandUsage(c.lookup("co"))
+	if !strings.Contains(stderr.String(), "别名: co, switch\n使用方法: cmd checkout [选项]") {
+		t.Errorf("expected the aliases in the usage of checkout, found %q", stderr.String())
+	}
+
+	for _, test := range []struct {
+		name     string
+		register func()
+	}{
+		{"alias of another command", func() { c.OnAlias("rm", []string{"co"}, "", &testCmd2{}, nil) }},
+		{"name of another command", func() { c.OnAlias("rm", []string{"remove"}, "", &testCmd2{}, nil) }},
+		{"own name", func() { c.OnAlias("rm", []string{"rm"}, "", &testCmd2{}, nil) }},
+		{"repeated alias", func() { c.OnAlias("rm", []string{"r", "r"}, "", &testCmd2{}, nil) }},
+		{"name taken by an alias", func() { c.On("switch", "", &testCmd2{}, nil) }},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s: expected to panic", test.name)
+				}
+			}()
+			test.register()
+		}()
+	}
+	if c.lookup("rm") != nil {
+		t.Error("no command is expected to be registered by a failing OnAlias")
+	}
+}
+
+// Tests if the output and the error of a command are captured.
+func TestRunCaptured(t *testing.T) {
+	stdout := captureStdOutput(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.OnOutput("status", "", &testOutputCmd{}, []string{})
+	errFail := &Error{Code: 3, Message: "boom"}
+	c.On("fail", "", &testErrCmd{err: errFail}, []string{})
+
+	out, errOut, err := c.RunCaptured("status", nil)
+	if out != "{web true}\n" || errOut != "" || err != nil {
+		t.Errorf("expected the status, found %q %q %v", out, errOut, err)
+	}
+	out, errOut, err = c.RunCaptured("fail", []string{"arg"})
+	if out != "" || errOut != "FATAL: fail: boom\n" || err != errFail {
+		t.Errorf("expected the error of the command, found %q %q %v", out, errOut, err)
+	}
+	_, errOut, err = c.RunCaptured("unknown", nil)
+	if err == nil || !strings.Contains(errOut, "unknown") {
+		t.Errorf("expected the unknown command to fail, found %q %v", errOut, err)
+	}
+	if stdout.String() != "" {
+		t.Errorf("nothing is expected to be written to StdOutput, found %q", stdout.String())
+	}
+}
+
+// Tests if the FATAL message is prefixed with the path of the failing
+// command and the error code is used as the exit code.
+func TestRunErrorPrefix(t *testing.T) {
+	resetForTesting("command1")
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	On("command1", "", &testErrCmd{err: &Error{Code: 3, Message: "boom"}}, []string{})
+	Parse()
+	Run()
+	if !strings.Contains(stderr.String(), "FATAL: command1: boom") {
+		t.Errorf("expected 'FATAL: command1: boom', found %q", stderr.String())
+	}
+	if *code != 3 {
+		t.Errorf("expected exit code 3, found %v", *code)
+	}
+}
+
+// Tests if a forwarding command runs its target with the forwarded
+// flags, and its help shows its own description with the target's flags.
+func TestForward(t *testing.T) {
+	resetForTesting("logs", "somearg")
+
+	c1 := &testCmd1{}
+	On("command1", "description of command1", c1, []string{})
+	Default.OnForward("logs", "description of logs", "command1", []string{"-flag1"})
+	Parse()
+	Run()
+	if !c1.run {
+		t.Error("command 'command1' was expected to run, but it didn't")
+	}
+	if !*c1.flag1 {
+		t.Errorf("flag1 should be forwarded: expected true, found %v", *c1.flag1)
+	}
+	if len(Default.args) != 1 || Default.args[0] != "somearg" {
+		t.Errorf("expected the argument 'somearg', found %v", Default.args)
+	}
+
+	resetForTesting("logs", "-h")
+	stderr := captureStdErr(t)
+	c1 = &testCmd1{}
+	On("command1", "description of command1", c1, []string{})
+	Default.OnForward("logs", "description of logs", "command1", []string{"-flag1"})
+	Parse()
+	Run()
+	if c1.run {
+		t.Error("command 'command1' was not expected to run, but it did")
+	}
+	usage := stderr.String()
+	if !strings.Contains(usage, "description of logs") || strings.Contains(usage, "description of command1") {
+		t.Errorf("expected the description of logs, found %q", usage)
+	}
+	if !strings.Contains(usage, "-flag1") {
+		t.Errorf("expected the flags of command1, found %q", usage)
+	}
+}
+
+// Tests if the first registered command runs if none is given, unless
+// a default command is set explicitly.
+func TestDefaultToFirst(t *testing.T) {
+	resetForTesting()
+
+	c1 := &testCmd1{}
+	c2 := &testCmd2{}
+	On("command1", "", c1, []string{})
+	On("command2", "", c2, []string{})
+	Default.SetDefaultToFirst(true)
+	Parse()
+	Run()
+	if !c1.run {
+		t.Error("command 'command1' was expected to run, but it didn't")
+	}
+
+	resetForTesting()
+	c1 = &testCmd1{}
+	c2 = &testCmd2{}
+	On("command1", "", c1, []string{})
+	On("command2", "", c2, []string{})
+	Default.SetDefaultToFirst(true)
+	Default.SetDefaultCommand("command2")
+	Parse()
+	Run()
+	if c1.run || !c2.run {
+		t.Error("command 'command2' was expected to run instead of 'command1'")
+	}
+}
+
+// Tests if a command is listed under each of its categories.
+func TestCategories(t *testing.T) {
+	resetForTesting()
+	stderr := captureStdErr(t)
+
+	On("command1", "description of command1", &testCmd1{}, []string{})
+	On("logs", "description of logs", &testCmd2{}, []string{})
+	Default.SetCategories("logs", "Debugging", "Observability", "Debugging")
+	Usage()
+
+	usage := stderr.String()
+	if strings.Count(usage, "description of logs") != 2 {
+		t.Errorf("logs is expected to be listed twice, found %q", usage)
+	}
+	debugging := strings.Index(usage, "Debugging:")
+	observability := strings.Index(usage, "Observability:")
+	if debugging < 0 || observability < debugging {
+		t.Errorf("expected the Debugging and Observability categories in order, found %q", usage)
+	}
+	if strings.Index(usage, "description of command1") > debugging {
+		t.Errorf("uncategorized commands are expected first, found %q", usage)
+	}
+}
+
+// Tests if the usage of a passthrough command shows the "--" separator.
+func TestPassthroughUsage(t *testing.T) {
+	resetForTesting("exec", "-flag1", "--", "ls", "-l")
+	stderr := captureStdErr(t)
+
+	c1 := &testCmd1{}
+	On("exec", "", c1, []string{})
+	Default.SetPassthrough("exec", "<command> [args...]")
+	Parse()
+	if len(Default.args) != 2 || Default.args[0] != "ls" || Default.args[1] != "-l" {
+		t.Errorf("expected the arguments after '--', found %v", Default.args)
+	}
+
+	Default.SubcommandUsage(Default.lookup("exec"))
+	if !strings.Contains(stderr.String(), "exec [选项] -- <command> [args...]") {
+		t.Errorf("expected the '--' separator in the usage, found %q", stderr.String())
+	}
+}
+
+// Tests if the path of a command in its usage can be customized.
+func TestUsagePathFormatter(t *testing.T) {
+	resetForTesting()
+	stderr := captureStdErr(t)
+
+	On("command1", "", &testCmd1{}, []string{})
+	Default.SubcommandUsage(Default.lookup("command1"))
+	if !strings.Contains(stderr.String(), "使用方法: cmd command1 [选项]") {
+		t.Errorf("expected the default path, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	Default.SetUsagePathFormatter(func(path []string) string {
+		return "app:" + strings.Join(path, "/")
+	})
+	Default.SubcommandUsage(Default.lookup("command1"))
+	if !strings.Contains(stderr.String(), "使用方法: app:command1 [选项]") {
+		t.Errorf("expected the formatted path, found %q", stderr.String())
+	}
+}
+
+// Tests if clones of the same commands can be parsed in parallel.
+func TestClone(t *testing.T) {
+	base := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	base.On("command1", "", &testStatelessCmd{}, []string{})
+	base.flags.String("global1", "", "")
+
+	t.Run("group", func(t *testing.T) {
+		for _, arg := range []string{"arg1", "arg2"} {
+			arg := arg
+			t.Run(arg, func(t *testing.T) {
+				t.Parallel()
+				c := base.Clone()
+				if c.flags.Lookup("global1") != nil {
+					t.Error("the global flags of a clone are expected to be empty")
+				}
+				c.Parse([]string{"command1", "-flag", arg})
+				if len(c.args) != 1 || c.args[0] != arg {
+					t.Errorf("expected %s, found %v", arg, c.args)
+				}
+				if c.matchingCmd == base.list[0] || c.matchingCmd.name != "command1" {
+					t.Error("the clone is expected to copy the registered command")
+				}
+			})
+		}
+	})
+	if base.matchingCmd != nil {
+		t.Error("parsing a clone is not expected to change the original")
+	}
+
+	remote := base.OnGroup("remote", "")
+	remote.On("add", "", &testStatelessCmd{}, nil)
+	remote.OnForward("a", "", "add", []string{"-flag"})
+	base.OnForward("logs", "", "command1", []string{"-flag"})
+	c := base.Clone()
+	add := c.find("remote add")
+	if add == base.find("remote add") || add.parent != c.lookup("remote") || c.owner(add).parent != add.parent {
+		t.Error("the clone is expected to copy the groups")
+	}
+	for _, args := range [][]string{{"logs", "x"}, {"remote", "a", "x"}} {
+		if err := c.ParseErr(args); err != nil || c.matchingTarget == nil || !reflect.DeepEqual(c.args, []string{"x"}) {
+			t.Errorf("%q: expected the forwarding command to parse in the clone, found %v", args, err)
+		}
+	}
+}
+
+// Tests if clones get their own copies of the commands implementing
+// Cloner, so that they can be parsed and run concurrently.
+func TestCloneStateful(t *testing.T) {
+	base := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	base.SetErrorHandling(flag.ContinueOnError)
+	base.On("command1", "", &testStatefulCmd{}, []string{})
+	base.Annotate("command1", "owner", "base")
+
+	t.Run("group", func(t *testing.T) {
+		for _, value := range []string{"value1", "value2", "value3"} {
+			value := value
+			t.Run(value, func(t *testing.T) {
+				t.Parallel()
+				c := base.Clone()
+				c.Annotate("command1", "owner", value)
+				for i := 0; i < 10; i++ {
+					if err := c.ParseErr([]string{"command1", "-value", value}); err != nil {
+						t.Fatal(err)
+					}
+					c.Run()
+					if cmd := c.lookup("command1").command.(*testStatefulCmd); cmd.ran != value {
+						t.Errorf("expected %s, found %s", value, cmd.ran)
+					}
+				}
+			})
+		}
+	})
+	if owner := base.lookup("command1").annotations["owner"]; owner != "base" {
+		t.Errorf("annotating a clone is not expected to change the original, found %s", owner)
+	}
+	if cmd := base.lookup("command1").command.(*testStatefulCmd); cmd.ran != "" {
+		t.Errorf("running a clone is not expected to run the original, found %s", cmd.ran)
+	}
+}
+
+// Tests if the exit code is derived by the exit coder, falling back to
+// the code of the error.
+func TestExitCoder(t *testing.T) {
+	errTransient := errors.New("transient")
+	for _, test := range []struct {
+		err  error
+		code int
+	}{
+		{errTransient, 75},
+		{errors.New("other"), -1},
+		{&Error{Code: 3, Message: "other"}, 3},
+	} {
+		resetForTesting("command1")
+		captureStdErr(t)
+		code := captureExit(t)
+
+		On("command1", "", &testErrCmd{err: test.err}, []string{})
+		Default.SetExitCoder(func(err error) int {
+			if errors.Is(err, errTransient) {
+				return 75
+			}
+			return 0
+		})
+		Parse()
+		Run()
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.err, test.code, *code)
+		}
+	}
+}
+
+// Tests if wrong invocations exit with the usage error code, but
+// failures of the command keep their code.
+func TestUsageErrorCode(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"command3"}, 64},
+		{[]string{"required"}, 64},
+		{[]string{"required", "-nope"}, 64},
+		{[]string{"exclusive", "-int=1", "-int64=1"}, 64},
+		{[]string{"missing"}, 64},
+		{[]string{"loop"}, 64},
+		{[]string{"fail"}, 3},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("required", "", &testCmd1{}, []string{"flag1"})
+		c.On("exclusive", "", &testAllFlagsCmd{}, []string{})
+		c.MarkFlagsMutuallyExclusive("exclusive", "int", "int64")
+		c.OnForward("missing", "", "nothing", nil)
+		c.OnForward("loop", "", "loop", nil)
+		c.On("fail", "", &testErrCmd{err: &Error{Code: 3, Message: "boom"}}, []string{})
+		c.SetUsageErrorCode(64)
+		c.Parse(test.args)
+		if *code == -100 {
+			c.Run()
+		}
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+	}
+}
+
+// Tests if invalid flags exit with 2 like the flag package, and the
+// other wrong invocations with 1, unless SetUsageErrorCode is used.
+func TestDefaultUsageErrorCode(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("required", "", &testCmd1{}, []string{"flag1"})
+
+	c.Parse([]string{"required", "-nope"})
+	if *code != 2 {
+		t.Errorf("expected exit code 2 for an invalid flag, found %d", *code)
+	}
+	c.Parse([]string{"required"})
+	if *code != 1 {
+		t.Errorf("expected exit code 1 for a missing flag, found %d", *code)
+	}
+}
+
+// Tests if the custom usage is used when no commands are registered.
+func TestTopLevelUsage(t *testing.T) {
+	resetForTesting()
+	stderr := captureStdErr(t)
+
+	flag.String("global1", "default-global1", "Description about global1")
+	Default.SetTopLevelUsage(func(w io.Writer) {
+		fmt.Fprintln(w, "cmd converts files")
+		flag.CommandLine.SetOutput(w)
+		flag.PrintDefaults()
+	})
+	Usage()
+	if !strings.HasPrefix(stderr.String(), "cmd converts files\n") || !strings.Contains(stderr.String(), "global1") {
+		t.Errorf("expected the custom usage, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	On("command1", "", &testCmd1{}, []string{})
+	Usage()
+	if strings.Contains(stderr.String(), "cmd converts files") {
+		t.Errorf("the custom usage is not expected with commands, found %q", stderr.String())
+	}
+}
+
+// Tests if help is printed to stdout on request.
+func TestShowHelp(t *testing.T) {
+	stdout := captureStdOutput(t)
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "the first command", &testCmd1{}, []string{})
+	if err := c.ShowHelp(""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "command1") || !strings.Contains(stdout.String(), "the first command") {
+		t.Errorf("expected the top-level usage, found %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := c.ShowHelp("command1"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "-flag1") {
+		t.Errorf("expected the usage of command1, found %q", stdout.String())
+	}
+	if stderr.String() != "" {
+		t.Errorf("nothing is expected on stderr, found %q", stderr.String())
+	}
+
+	if err := c.ShowHelp("command3"); err == nil {
+		t.Error("an unknown command is expected to fail")
+	}
+
+	// neither the globals nor the output of the global flags change
+	var own bytes.Buffer
+	c.flags.String("global1", "", "the global flag")
+	c.flags.SetOutput(&own)
+	stdout.Reset()
+	c.SetHelpRenderer(testStdErrRenderer{})
+	if err := c.ShowHelp(""); err != nil {
+		t.Fatal(err)
+	}
+	c.SetHelpRenderer(nil)
+	if err := c.ShowHelp(""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout.String(), "stderr unchanged\n") || !strings.Contains(stdout.String(), "the global flag") {
+		t.Errorf("expected the usage without swapping StdErr, found %q", stdout.String())
+	}
+	if c.flags.Output() != &own || own.Len() != 0 {
+		t.Error("the output of the global flags is not expected to change")
+	}
+}
+
+// testStdErrRenderer reports whether StdErr is left alone while
+// rendering to another writer.
+type testStdErrRenderer struct{}
+
+func (testStdErrRenderer) RenderUsage(c *Commands, w io.Writer) {
+	if StdErr != w {
+		fmt.Fprintln(w, "stderr unchanged")
+	}
+}
+
+func (testStdErrRenderer) RenderSubcommandUsage(c *Commands, cmd CmdInfo, w io.Writer) {
+}
+
+// Tests if the pre-parse hook can register commands before parsing.
+func TestParsePreHook(t *testing.T) {
+	resetForTesting("experimental")
+	defer SetDefaultParsePreHook(nil)
+
+	c1 := &testCmd1{}
+	SetDefaultParsePreHook(func(c *Commands) {
+		if c != Default {
+			t.Error("the hook is expected to be called with Default")
+		}
+		c.On("experimental", "", c1, []string{})
+	})
+	ParseAndRun()
+	if !c1.run {
+		t.Error("the command registered by the hook was expected to run, but it didn't")
+	}
+}
+
+// Resets os.Args, the default flag set and the default commands.
 func resetForTesting(args ...string) {
-	Default.list = nil
 	os.Args = append([]string{"cmd"}, args...)
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	Default = New(os.Args[0], flag.CommandLine)
+}
+
+// Records the code passed to Exit instead of exiting until the test
+// finishes, the code is -100 if Exit isn't called.
+func captureExit(t *testing.T) *int {
+	code := -100
+	old := Exit
+	Exit = func(c int) { code = c }
+	t.Cleanup(func() { Exit = old })
+	return &code
+}
+
+// Redirects StdErr to a buffer until the test finishes.
+func captureStdErr(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	old := StdErr
+	StdErr = &buf
+	t.Cleanup(func() { StdErr = old })
+	return &buf
 }
 
 // testCmd1 is a test sub command.
@@ -185,3 +991,50 @@ func (cmd *testCmd2) Run(args []string) error {
 	cmd.run = true
 	return nil
 }
+
+// testErrCmd is a test sub command which fails with err.
+type testErrCmd struct {
+	err error
+}
+
+func (cmd *testErrCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testErrCmd) Run(args []string) error {
+	return cmd.err
+}
+
+// testStatelessCmd is a test sub command which keeps no state, so it
+// can be parsed concurrently.
+type testStatelessCmd struct{}
+
+func (cmd *testStatelessCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.Bool("flag", false, "")
+	return fs
+}
+
+func (cmd *testStatelessCmd) Run(args []string) error {
+	return nil
+}
+
+// testStatefulCmd is a test sub command binding its flag to a field, so
+// it is copied for each clone.
+type testStatefulCmd struct {
+	value string
+	ran   string
+}
+
+func (cmd *testStatefulCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&cmd.value, "value", "", "")
+	return fs
+}
+
+func (cmd *testStatefulCmd) Run(args []string) error {
+	cmd.ran = cmd.value
+	return nil
+}
+
+func (cmd *testStatefulCmd) CloneCmd() interface{} {
+	return &testStatefulCmd{}
+}
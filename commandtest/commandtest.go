@@ -0,0 +1,95 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package commandtest provides utilities for testing programs built
+// on the default instance of the command package.
+package commandtest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mei-rune/command"
+)
+
+// exitCode is the panic value used to unwind from command.Exit.
+type exitCode int
+
+// Runs the default commands with args, as if they were given on the
+// command line after the program name. The output is captured instead
+// of written to the process' stdout and stderr, and exiting is turned
+// into a returned *command.Error carrying the exit code, a zero exit
+// code results in a nil error. The global flags are parsed by a copy of
+// flag.CommandLine which doesn't exit the process, whatever its error
+// handling.
+func Run(t testing.TB, args []string) (stdout, stderr string, err error) {
+	t.Helper()
+
+	var outBuf, errBuf bytes.Buffer
+	oldOut, oldErr, oldExit, oldArgs := command.StdOutput, command.StdErr, command.Exit, os.Args
+	command.StdOutput = &outBuf
+	command.StdErr = &errBuf
+	command.Exit = func(code int) {
+		panic(exitCode(code))
+	}
+	os.Args = append([]string{oldArgs[0]}, args...)
+	oldFlags := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet(oldFlags.Name(), flag.PanicOnError)
+	oldFlags.VisitAll(func(f *flag.Flag) {
+		flag.CommandLine.Var(f.Value, f.Name, f.Usage)
+		flag.CommandLine.Lookup(f.Name).DefValue = f.DefValue
+	})
+	// flag.CommandLine prints the usage before it panics with a failure
+	var failed bool
+	flag.CommandLine.Usage = func() {
+		failed = true
+		flag.Usage()
+	}
+	flag.CommandLine.SetOutput(&errBuf)
+	defer func() {
+		command.StdOutput, command.StdErr, command.Exit, os.Args = oldOut, oldErr, oldExit, oldArgs
+		flag.CommandLine = oldFlags
+	}()
+
+	err = run(&failed)
+	return outBuf.String(), errBuf.String(), err
+}
+
+func run(flagsFailed *bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			code, ok := r.(exitCode)
+			if !ok {
+				// the global flags failed to parse, exit with the codes
+				// of flag.ExitOnError
+				e, isErr := r.(error)
+				if !isErr || !*flagsFailed {
+					panic(r)
+				}
+				code = 2
+				if e == flag.ErrHelp {
+					code = 0
+				}
+			}
+			if code != 0 {
+				err = &command.Error{Code: int(code), Message: fmt.Sprintf("exit status %d", code)}
+			}
+		}
+	}()
+	command.ParseAndRun()
+	return nil
+}
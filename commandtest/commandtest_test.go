@@ -0,0 +1,116 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commandtest
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/mei-rune/command"
+)
+
+// echoCmd is a test sub command which prints its arguments.
+type echoCmd struct{}
+
+func (cmd *echoCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *echoCmd) Run(args []string) error {
+	if len(args) == 0 {
+		return &command.Error{Code: 2, Message: "nothing to echo"}
+	}
+	command.Println(strings.Join(args, " "))
+	return nil
+}
+
+// The flag.CommandLine of the test binary, which exits on errors.
+var commandLine = flag.CommandLine
+
+func reset() {
+	flag.CommandLine = flag.NewFlagSet("cmd", flag.ContinueOnError)
+	command.Default = command.New("cmd", flag.CommandLine)
+	command.On("echo", "prints the arguments", &echoCmd{}, []string{})
+}
+
+// Tests if the output of a successful run is captured.
+func TestRun(t *testing.T) {
+	reset()
+	stdout, stderr, err := Run(t, []string{"echo", "hello", "world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "hello world\n" {
+		t.Errorf("expected 'hello world', found %q", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("no error output is expected, found %q", stderr)
+	}
+}
+
+// Tests if a failing run returns the exit code instead of exiting.
+func TestRunExitCode(t *testing.T) {
+	reset()
+	_, stderr, err := Run(t, []string{"echo"})
+	var e *command.Error
+	if !errors.As(err, &e) || e.Code != 2 {
+		t.Fatalf("expected exit code 2, found %v", err)
+	}
+	if !strings.Contains(stderr, "nothing to echo") {
+		t.Errorf("expected the error message in stderr, found %q", stderr)
+	}
+
+	reset()
+	_, _, err = Run(t, []string{"unknown"})
+	if !errors.As(err, &e) || e.Code != 1 {
+		t.Errorf("expected exit code 1 for an unknown command, found %v", err)
+	}
+}
+
+// Tests if the failures of the global flags of the default
+// flag.CommandLine are returned instead of exiting the process.
+func TestRunGlobalFlags(t *testing.T) {
+	defer reset()
+	flag.CommandLine = commandLine
+	command.Default = command.New("cmd", flag.CommandLine)
+	command.On("echo", "prints the arguments", &echoCmd{}, []string{})
+
+	_, stderr, err := Run(t, []string{"-no-such-flag", "echo", "hello"})
+	var e *command.Error
+	if !errors.As(err, &e) || e.Code != 2 {
+		t.Errorf("expected exit code 2 for an undefined flag, found %v", err)
+	}
+	if !strings.Contains(stderr, "no-such-flag") || !strings.Contains(stderr, "echo") {
+		t.Errorf("expected the failure and the usage in stderr, found %q", stderr)
+	}
+
+	stdout, stderr, err := Run(t, []string{"-h"})
+	if err != nil {
+		t.Errorf("expected no error for -h, found %v", err)
+	}
+	if stdout != "" || !strings.Contains(stderr, "prints the arguments") {
+		t.Errorf("expected the usage in stderr, found %q, %q", stdout, stderr)
+	}
+
+	stdout, _, err = Run(t, []string{"echo", "hello"})
+	if err != nil || stdout != "hello\n" {
+		t.Errorf("expected hello, found %q, %v", stdout, err)
+	}
+	if flag.CommandLine != commandLine {
+		t.Error("flag.CommandLine is expected to be restored")
+	}
+}
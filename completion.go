@@ -0,0 +1,105 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"sort"
+)
+
+// completionFlag describes a flag in the completion spec.
+type completionFlag struct {
+	Name string `json:"name"`
+	// The flag takes a value, i.e. it isn't a bool flag.
+	TakesValue  bool   `json:"takes_value"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// completionArg lists the values allowed for a positional argument.
+type completionArg struct {
+	Index  int      `json:"index"`
+	Values []string `json:"values"`
+}
+
+// completionCommand describes a sub-command in the completion spec.
+type completionCommand struct {
+	Name        string           `json:"name"`
+	Aliases     []string         `json:"aliases,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Flags       []completionFlag `json:"flags"`
+	Args        []completionArg  `json:"args,omitempty"`
+}
+
+// completionSpec is the document written by GenCompletionSpec.
+type completionSpec struct {
+	Program  string              `json:"program"`
+	Flags    []completionFlag    `json:"flags"`
+	Commands []completionCommand `json:"commands"`
+}
+
+// Returns the flags of fs for the completion spec.
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	flags := []completionFlag{}
+	fs.VisitAll(func(f *flag.Flag) {
+		typ := flagType(f.Value)
+		flags = append(flags, completionFlag{
+			Name:        f.Name,
+			TakesValue:  typ != "bool",
+			Type:        typ,
+			Description: f.Usage,
+		})
+	})
+	return flags
+}
+
+// Writes a JSON spec of the global flags and the sub-commands listed by
+// List, with their flags and the values allowed for their arguments,
+// see AllowedArgValues, to generate the completion of any shell from.
+func (c *Commands) GenCompletionSpec(w io.Writer) error {
+	spec := completionSpec{
+		Program:  c.program,
+		Flags:    completionFlags(c.flags),
+		Commands: []completionCommand{},
+	}
+	err := c.walk(func(subcmd *cmdInstance) error {
+		if !c.authorized(subcmd) {
+			return nil
+		}
+		cmd := completionCommand{Name: subcmd.pathName(), Aliases: subcmd.aliases, Description: subcmd.description, Flags: []completionFlag{}}
+		if fs, target, err := c.commandFlags(subcmd.pathName()); err == nil {
+			cmd.Flags = completionFlags(fs)
+			for index, values := range target.allowedArgs {
+				cmd.Args = append(cmd.Args, completionArg{Index: index, Values: values})
+			}
+			sort.Slice(cmd.Args, func(i, j int) bool {
+				return cmd.Args[i].Index < cmd.Args[j].Index
+			})
+		}
+		spec.Commands = append(spec.Commands, cmd)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
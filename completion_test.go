@@ -0,0 +1,67 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// Tests if the completion spec lists the commands, their flags and the
+// allowed argument values.
+func TestGenCompletionSpec(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.flags.Bool("verbose", false, "verbose output")
+	c.On("login", "description of login", &testStringCmd{}, []string{})
+	c.On("set", "", &testCmd1{}, []string{})
+	c.AllowedArgValues("set", 1, []string{"on", "off"})
+	c.AllowedArgValues("set", 0, []string{"a", "b"})
+	c.OnForward("l", "", "login", nil)
+
+	var buf bytes.Buffer
+	if err := c.GenCompletionSpec(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var spec completionSpec
+	if err := json.Unmarshal(buf.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	expected := completionSpec{
+		Program: "cmd",
+		Flags:   []completionFlag{{Name: "verbose", Type: "bool", Description: "verbose output"}},
+		Commands: []completionCommand{
+			{Name: "login", Description: "description of login", Flags: []completionFlag{
+				{Name: "token", TakesValue: true, Type: "string", Description: "Description about token"},
+			}},
+			{Name: "set", Flags: []completionFlag{{Name: "flag1", Type: "bool", Description: "Description about flag1"}}, Args: []completionArg{
+				{Index: 0, Values: []string{"a", "b"}},
+				{Index: 1, Values: []string{"on", "off"}},
+			}},
+			{Name: "l", Flags: []completionFlag{
+				{Name: "token", TakesValue: true, Type: "string", Description: "Description about token"},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(spec, expected) {
+		t.Errorf("expected\n%+v\nfound\n%+v", expected, spec)
+	}
+
+	if err := c.GenCompletionSpec(&failingWriter{}); err == nil {
+		t.Error("a failing write is expected to be returned")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ConstraintKind is the kind of relation a Constraint declares
+// between flags.
+type ConstraintKind int
+
+const (
+	// At most one of the flags may be set.
+	MutuallyExclusive ConstraintKind = iota
+	// At least one of the flags must be set.
+	OneRequired
+	// Either all or none of the flags must be set.
+	RequiredTogether
+	// If the first flag is set, the others must be set too.
+	Requires
+	// At least N of the flags must be set.
+	AtLeast
+)
+
+// Constraint is a relation between the flags of a sub-command which
+// is checked by Parse.
+type Constraint struct {
+	Kind  ConstraintKind
+	Flags []string
+	// The number of flags required by AtLeast.
+	N int
+}
+
+// Describes the constraint, e.g. "-cert 需要同时指定 -key".
+func (ct Constraint) String() string {
+	switch ct.Kind {
+	case MutuallyExclusive:
+		return joinFlags(ct.Flags) + " 不能同时指定"
+	case OneRequired:
+		return joinFlags(ct.Flags) + " 至少需要指定一个"
+	case RequiredTogether:
+		return joinFlags(ct.Flags) + " 必须同时指定"
+	case Requires:
+		return joinFlags(ct.Flags[:1]) + " 需要同时指定 " + joinFlags(ct.Flags[1:])
+	case AtLeast:
+		return fmt.Sprintf("%s 至少需要指定 %d 个", joinFlags(ct.Flags), ct.N)
+	}
+	return joinFlags(ct.Flags)
+}
+
+// Returns an error describing the constraint if the set flags violate
+// it.
+func (ct Constraint) check(set map[string]bool) error {
+	count := 0
+	for _, name := range ct.Flags {
+		if set[name] {
+			count++
+		}
+	}
+	var ok bool
+	switch ct.Kind {
+	case MutuallyExclusive:
+		ok = count <= 1
+	case OneRequired:
+		ok = count >= 1
+	case RequiredTogether:
+		ok = count == 0 || count == len(ct.Flags)
+	case Requires:
+		ok = !set[ct.Flags[0]] || count == len(ct.Flags)
+	case AtLeast:
+		ok = count >= ct.N
+	}
+	if ok {
+		return nil
+	}
+	return &constraintError{ct}
+}
+
+// constraintError is a violation of a Constraint.
+type constraintError struct {
+	Constraint
+}
+
+func (e *constraintError) Error() string {
+	return e.String()
+}
+
+func joinFlags(names []string) string {
+	return "-" + strings.Join(names, ", -")
+}
+
+func (c *Commands) addConstraint(cmdName string, ct Constraint) {
+	subcmd := c.mustLookup(cmdName)
+	if len(ct.Flags) < 2 && ct.Kind != OneRequired && ct.Kind != AtLeast {
+		panic(errors.New("命令 '" + cmdName + "' 的约束至少需要两个选项"))
+	}
+	if ct.Kind == AtLeast && (ct.N < 1 || ct.N > len(ct.Flags)) {
+		panic(fmt.Errorf("命令 '%s' 的约束无法满足: %d 个选项中至少指定 %d 个", cmdName, len(ct.Flags), ct.N))
+	}
+	subcmd.constraints = append(subcmd.constraints, ct)
+}
+
+// Declares that at most one of the flags of the named sub-command may
+// be set.
+func (c *Commands) MarkFlagsMutuallyExclusive(cmdName string, flags ...string) {
+	c.addConstraint(cmdName, Constraint{Kind: MutuallyExclusive, Flags: flags})
+}
+
+// Declares that at least one of the flags of the named sub-command must
+// be set.
+func (c *Commands) MarkFlagsOneRequired(cmdName string, flags ...string) {
+	c.addConstraint(cmdName, Constraint{Kind: OneRequired, Flags: flags})
+}
+
+// Declares that either all or none of the flags of the named
+// sub-command must be set.
+func (c *Commands) MarkFlagsRequiredTogether(cmdName string, flags ...string) {
+	c.addConstraint(cmdName, Constraint{Kind: RequiredTogether, Flags: flags})
+}
+
+// Declares that if flag of the named sub-command is set, the flags in
+// requires must be set too, e.g. -cert requires -key.
+func (c *Commands) MarkFlagRequires(cmdName, flag string, requires ...string) {
+	c.addConstraint(cmdName, Constraint{Kind: Requires, Flags: append([]string{flag}, requires...)})
+}
+
+// Declares that at least n of the flags of the named sub-command must
+// be set.
+func (c *Commands) RequireAtLeast(cmdName string, n int, flags []string) {
+	c.addConstraint(cmdName, Constraint{Kind: AtLeast, Flags: flags, N: n})
+}
+
+// Restricts the positional argument at index of the named sub-command
+// to one of allowed, e.g. the format of `log <format>`. It is checked
+// only if the argument is given.
+func (c *Commands) AllowedArgValues(cmdName string, index int, allowed []string) {
+	subcmd := c.mustLookup(cmdName)
+	if index < 0 || len(allowed) == 0 {
+		panic(fmt.Errorf("命令 '%s' 的参数 %d 的可选值无效", cmdName, index))
+	}
+	if subcmd.allowedArgs == nil {
+		subcmd.allowedArgs = make(map[int][]string)
+	}
+	subcmd.allowedArgs[index] = allowed
+}
+
+// Returns an error for the first argument in args which isn't one of
+// the values allowed for it.
+func checkArgValues(args []string, allowedArgs map[int][]string) error {
+	for i, arg := range args {
+		allowed, ok := allowedArgs[i]
+		if !ok {
+			continue
+		}
+		found := false
+		for _, v := range allowed {
+			found = found || v == arg
+		}
+		if !found {
+			return fmt.Errorf("参数 '%s' 无效, 可选值: %s", arg, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// Returns the constraints declared between the flags of the named
+// sub-command.
+func (c *Commands) FlagConstraints(cmdName string) []Constraint {
+	target, _ := c.resolve(c.find(cmdName), nil)
+	if target == nil {
+		return nil
+	}
+	return target.constraints
+}
+
+// Returns the flags the named sub-command requires unconditionally, the
+// requirements depending on other flags are described by
+// FlagConstraints.
+func (c *Commands) RequiredFlags(cmdName string) []string {
+	target, _ := c.resolve(c.find(cmdName), nil)
+	if target == nil {
+		return nil
+	}
+	return append([]string(nil), target.requiredFlags...)
+}
+
+// Returns the violations of the constraints by the flags set in fs.
+func checkConstraints(fs *flag.FlagSet, constraints []Constraint) []error {
+	set := setFlags(fs)
+	var errs []error
+	for _, ct := range constraints {
+		if err := ct.check(set); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,146 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newConstraintCommands() *Commands {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	c.MarkFlagsMutuallyExclusive("all", "int", "int64")
+	c.MarkFlagsOneRequired("all", "uint", "uint64")
+	c.MarkFlagRequires("all", "string", "duration")
+	return c
+}
+
+// Tests if the declared constraints are checked by Parse.
+func TestFlagConstraints(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"all", "-uint=1"}, -100},
+		{[]string{"all", "-uint=1", "-string=a", "-duration=1s"}, -100},
+		{[]string{"all", "-uint=1", "-int=1", "-int64=1"}, 1},
+		{[]string{"all", "-int=1"}, 1},
+		{[]string{"all", "-uint64=1", "-string=a"}, 1},
+	} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+		newConstraintCommands().Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v: %s", test.args, test.code, *code, stderr)
+		}
+	}
+}
+
+// Tests if the required flags of a command and its forwards are listed.
+func TestRequiredFlags(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{"string", "int"})
+	c.OnForward("a", "", "all", nil)
+
+	for _, name := range []string{"all", "a"} {
+		if required := c.RequiredFlags(name); !reflect.DeepEqual(required, []string{"string", "int"}) {
+			t.Errorf("%s: expected string and int, found %q", name, required)
+		}
+	}
+	c.RequiredFlags("all")[0] = "changed"
+	if c.RequiredFlags("all")[0] != "string" {
+		t.Error("the required flags are not expected to be changed through the result")
+	}
+	if required := c.RequiredFlags("unknown"); required != nil {
+		t.Errorf("expected no flags for an unknown command, found %q", required)
+	}
+}
+
+// Tests if the declared constraints appear in the usage.
+func TestFlagConstraintsUsage(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := newConstraintCommands()
+	if len(c.FlagConstraints("all")) != 3 {
+		t.Fatalf("expected 3 constraints, found %v", c.FlagConstraints("all"))
+	}
+	c.SubcommandUsage(c.lookup("all"))
+	for _, expected := range []string{
+		"约束:",
+		"-int, -int64 不能同时指定",
+		"-uint, -uint64 至少需要指定一个",
+		"-string 需要同时指定 -duration",
+	} {
+		if !strings.Contains(stderr.String(), expected) {
+			t.Errorf("expected %q in the usage, found %q", expected, stderr.String())
+		}
+	}
+}
+
+// Tests if at least the required number of flags must be set.
+func TestRequireAtLeast(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"all", "-int=1", "-string=a"}, -100},
+		{[]string{"all", "-int=1", "-string=a", "-bool"}, -100},
+		{[]string{"all", "-int=1"}, 1},
+		{[]string{"all", "-int=1", "-uint=1"}, 1},
+	} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("all", "", &testAllFlagsCmd{}, []string{})
+		c.RequireAtLeast("all", 2, []string{"bool", "int", "string"})
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+		if test.code == 1 && !strings.Contains(stderr.String(), "-bool, -int, -string 至少需要指定 2 个") {
+			t.Errorf("%v: expected the violated constraint, found %q", test.args, stderr.String())
+		}
+	}
+}
+
+// Tests if the positional arguments are checked against the allowed
+// values.
+func TestAllowedArgValues(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"log"}, -100},
+		{[]string{"log", "json"}, -100},
+		{[]string{"log", "text", "anything"}, -100},
+		{[]string{"log", "xml"}, 1},
+	} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("log", "", &testCmd1{}, []string{})
+		c.AllowedArgValues("log", 0, []string{"text", "json"})
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+		if test.code != -100 && !strings.Contains(stderr.String(), "可选值: text, json") {
+			t.Errorf("%v: expected the allowed values in the error, found %q", test.args, stderr.String())
+		}
+	}
+}
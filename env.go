@@ -0,0 +1,85 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"runtime"
+	"strings"
+)
+
+// The values of flags whose names contain one of these are redacted by
+// the env sub-command.
+var secretFlagNames = []string{"password", "passwd", "secret", "token", "key"}
+
+// Registers the `env` sub-command, which prints diagnostics to paste
+// into bug reports: the program name, the version set by SetVersion,
+// the go version, os and arch, and the values of the global flags.
+// The values of flags which look like secrets, e.g. -token, are
+// redacted.
+func (c *Commands) EnableEnvCommand() {
+	c.On("env", "显示运行环境的诊断信息", &envCmd{c: c}, nil)
+}
+
+// envCmd is the sub command registered by EnableEnvCommand.
+type envCmd struct {
+	c *Commands
+}
+
+func (cmd *envCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *envCmd) Run(args []string) error {
+	c := cmd.c
+	Printf("程序: %s\n", c.program)
+	if c.version != nil {
+		Printf("版本: %s", c.version.Version)
+		if c.version.Commit != "" {
+			Printf(" (%s)", c.version.Commit)
+		}
+		Println()
+	}
+	Printf("Go: %s\n", runtime.Version())
+	Printf("系统: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	first := true
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if first {
+			Println("全局选项:")
+			first = false
+		}
+		value := f.Value.String()
+		if (isSecretFlag(f.Name) || c.isSensitive(nil, f.Name)) && value != "" {
+			value = redacted
+		}
+		Printf("  -%s=%s\n", f.Name, value)
+	})
+	return nil
+}
+
+// Reports whether the flag named name looks like it holds a secret,
+// i.e. its name contains one of secretFlagNames, e.g. key in api-key
+// or apikey. Names like keyboard are redacted too, which is the safe
+// side for secrets.
+func isSecretFlag(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range secretFlagNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
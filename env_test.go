@@ -0,0 +1,79 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Tests if the env command prints the diagnostics with secrets
+// redacted.
+func TestEnvCommand(t *testing.T) {
+	stdout := captureStdOutput(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.flags.String("addr", "", "")
+	c.flags.String("api-token", "", "")
+	if err := c.flags.Parse([]string{"-addr=localhost:80", "-api-token=s3cr3t"}); err != nil {
+		t.Fatal(err)
+	}
+	c.SetVersion("1.2.0", "abc123")
+	c.EnableEnvCommand()
+	c.ParseAndRun([]string{"env"})
+
+	out := stdout.String()
+	for _, s := range []string{
+		"程序: cmd\n",
+		"版本: 1.2.0 (abc123)\n",
+		"Go: " + runtime.Version() + "\n",
+		"系统: " + runtime.GOOS + "/" + runtime.GOARCH + "\n",
+		"  -addr=localhost:80\n",
+		"  -api-token=******\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in the output, found %q", s, out)
+		}
+	}
+	if strings.Contains(out, "s3cr3t") {
+		t.Errorf("the token is expected to be redacted, found %q", out)
+	}
+}
+
+// Tests if the flags are told as secret by their names, erring on the
+// side of redacting.
+func TestIsSecretFlag(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"key":          true,
+		"api-key":      true,
+		"db_key":       true,
+		"apiKey":       true,
+		"tls.key":      true,
+		"api-token":    true,
+		"DB_PASSWORD":  true,
+		"apikey":       true,
+		"dbpassword":   true,
+		"accesstoken":  true,
+		"clientsecret": true,
+		"addr":         false,
+		"verbose":      false,
+	} {
+		if isSecretFlag(name) != expected {
+			t.Errorf("%s: expected %v", name, expected)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !plan9
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+// testPipeCmd is a test sub command failing to write to a closed pipe.
+type testPipeCmd struct{}
+
+func (cmd *testPipeCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testPipeCmd) Run(args []string) error {
+	return fmt.Errorf("write: %w", syscall.EPIPE)
+}
+
+// Tests if a command failing on a closed pipe exits quietly.
+func TestRunClosedPipe(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("completion", "", &testPipeCmd{}, []string{})
+
+	c.ParseAndRun([]string{"completion"})
+	if *code != -100 || stderr.String() != "" {
+		t.Errorf("expected a quiet exit, found code %d and %q", *code, stderr.String())
+	}
+}
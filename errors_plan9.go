@@ -0,0 +1,32 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Reports whether err is from writing to a pipe whose reader went away,
+// which Plan 9 reports as a write on a hungup channel.
+func isBrokenPipe(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "hungup channel")
+}
+
+// Returns the exit code of the process of err; Plan 9 has notes rather
+// than signals.
+func exitStatus(err *exec.ExitError) int {
+	return err.ExitCode()
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+)
+
+// Sets example invocations of the named sub-command, they are shown in
+// its usage and by the examples sub-command.
+func (c *Commands) SetExamples(name string, examples ...string) {
+	c.mustLookup(name).examples = examples
+}
+
+// Registers the `examples` sub-command, which prints the examples of
+// all sub-commands, or only those of the sub-command given as argument.
+func (c *Commands) EnableExamplesCommand() {
+	c.On("examples", "显示子命令的示例", &examplesCmd{c: c}, nil)
+}
+
+// examplesCmd is the sub command registered by EnableExamplesCommand.
+type examplesCmd struct {
+	c *Commands
+}
+
+func (cmd *examplesCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *examplesCmd) Run(args []string) error {
+	if len(args) > 1 {
+		return &Error{Code: 1, Message: "最多只能指定一个子命令", Help: true}
+	}
+	if len(args) == 1 {
+		subcmd := cmd.c.find(args[0])
+		if subcmd == nil || !cmd.c.authorized(subcmd) {
+			return &Error{Code: 1, Message: "未知的子命令: " + args[0]}
+		}
+		for _, example := range subcmd.examples {
+			Println(example)
+		}
+		return nil
+	}
+
+	first := true
+	for _, subcmd := range cmd.c.list {
+		if len(subcmd.examples) == 0 || !cmd.c.authorized(subcmd) {
+			continue
+		}
+		if !first {
+			Println()
+		}
+		first = false
+		Printf("%s: %s\n", subcmd.name, subcmd.description)
+		for _, example := range subcmd.examples {
+			Println("  " + example)
+		}
+	}
+	return nil
+}
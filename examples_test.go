@@ -0,0 +1,87 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// Redirects StdOutput to a buffer until the test finishes.
+func captureStdOutput(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	old := StdOutput
+	StdOutput = &buf
+	t.Cleanup(func() { StdOutput = old })
+	return &buf
+}
+
+// Tests if the examples command lists the examples of all commands or
+// of the given one.
+func TestExamplesCommand(t *testing.T) {
+	stdout := captureStdOutput(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.On("command2", "description of command2", &testCmd2{}, []string{})
+	c.SetExamples("command1", "cmd command1 -flag1", "cmd command1 arg")
+	c.SetExamples("command2", "cmd command2 -flag2")
+	c.EnableExamplesCommand()
+
+	c.ParseAndRun([]string{"examples"})
+	for _, expected := range []string{
+		"command1: description of command1\n  cmd command1 -flag1\n  cmd command1 arg\n",
+		"command2: description of command2\n  cmd command2 -flag2\n",
+	} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("expected %q, found %q", expected, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	c.ParseAndRun([]string{"examples", "command2"})
+	if stdout.String() != "cmd command2 -flag2\n" {
+		t.Errorf("expected only the examples of command2, found %q", stdout.String())
+	}
+}
+
+// Tests if the examples of the commands refused by the authorizer are
+// not printed.
+func TestExamplesCommandRefused(t *testing.T) {
+	stdout := captureStdOutput(t)
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.On("secret", "description of secret", &testCmd2{}, []string{})
+	c.SetExamples("command1", "cmd command1 -flag1")
+	c.SetExamples("secret", "cmd secret -flag2")
+	c.SetAuthorizer(func(name string) bool { return name != "secret" })
+	c.EnableExamplesCommand()
+
+	c.ParseAndRun([]string{"examples"})
+	if strings.Contains(stdout.String(), "secret") || !strings.Contains(stdout.String(), "command1") {
+		t.Errorf("expected only the examples of command1, found %q", stdout.String())
+	}
+
+	stdout.Reset()
+	c.ParseAndRun([]string{"examples", "secret"})
+	if stdout.Len() != 0 || *code != 1 {
+		t.Errorf("expected exit code 1 without examples, found %v %q", *code, stdout.String())
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// FlagMeta describes a flag of a sub-command.
+type FlagMeta struct {
+	Name string
+	// One of bool, int, int64, uint, uint64, float64, string,
+	// duration, or value if the type can't be inferred.
+	Type     string
+	Default  string
+	Usage    string
+	Required bool
+	// See MarkFlagSensitive, the default is redacted.
+	Sensitive bool
+}
+
+// Returns the flag set of the named sub-command, and the subcommand
+// which really runs it.
+func (c *Commands) commandFlags(name string) (*flag.FlagSet, *cmdInstance, error) {
+	target, _ := c.resolve(c.find(name), nil)
+	if target == nil {
+		return nil, nil, errors.New("命令 '" + name + "' 不存在")
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if !target.rawArgs {
+		fs = target.command.Flags(fs)
+	}
+	return fs, target, nil
+}
+
+// Returns the metadata of all flags of the named sub-command, sorted
+// by name.
+func (c *Commands) FlagMetadata(cmdName string) ([]FlagMeta, error) {
+	fs, target, err := c.commandFlags(cmdName)
+	if err != nil {
+		return nil, err
+	}
+	required := make(map[string]bool)
+	for _, name := range target.requiredFlags {
+		required[name] = true
+	}
+
+	subcmd := c.find(cmdName)
+	c.redactDefaults(subcmd, fs)
+	var metas []FlagMeta
+	fs.VisitAll(func(f *flag.Flag) {
+		metas = append(metas, FlagMeta{
+			Name:      f.Name,
+			Type:      flagType(f.Value),
+			Default:   f.DefValue,
+			Usage:     f.Usage,
+			Required:  required[f.Name],
+			Sensitive: c.isSensitive(subcmd, f.Name),
+		})
+	})
+	return metas, nil
+}
+
+// Returns the sub-commands defining each flag name, in the order of
+// registration, to audit flags reused with different meanings. The
+// global flags are listed under the empty name. Forwarding
+// sub-commands are skipped, their flags are those of their targets.
+func (c *Commands) FlagInventory() map[string][]string {
+	inventory := make(map[string][]string)
+	c.flags.VisitAll(func(f *flag.Flag) {
+		inventory[f.Name] = append(inventory[f.Name], "")
+	})
+	c.walk(func(subcmd *cmdInstance) error {
+		if subcmd.forward != "" {
+			return nil
+		}
+		fs, _, err := c.commandFlags(subcmd.pathName())
+		if err != nil {
+			return nil
+		}
+		fs.VisitAll(func(f *flag.Flag) {
+			inventory[f.Name] = append(inventory[f.Name], subcmd.pathName())
+		})
+		return nil
+	})
+	return inventory
+}
+
+// Reports whether the named flag of the matching sub-command was set
+// on the command line, as opposed to left at its default value.
+func (c *Commands) FlagChanged(name string) bool {
+	if c.matchingFlagSet == nil {
+		return false
+	}
+	return setFlags(c.matchingFlagSet)[name]
+}
+
+// Returns the names of the flags set in fs, a deprecated name set
+// standing for the name replacing it too, see DeprecatedAliasVar.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if d, ok := f.Value.(*deprecatedFlag); ok {
+			set[d.newName] = true
+		}
+	})
+	return set
+}
+
+// deprecatedFlag is the value of a deprecated flag name, sharing the
+// value of the flag which replaces it.
+type deprecatedFlag struct {
+	flag.Value
+	newName string
+}
+
+func (f *deprecatedFlag) IsBoolFlag() bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Defines a flag named newName storing into p, which is a pointer to a
+// bool, int, int64, uint, uint64, float64, string or time.Duration, or
+// a flag.Value, and a deprecated flag named oldName setting the same
+// value. When oldName is used on the command line, Parse prints a
+// warning pointing to newName.
+func DeprecatedAliasVar(fs *flag.FlagSet, p interface{}, oldName, newName, usage string) {
+	if err := defineVar(fs, p, newName, usage); err != nil {
+		panic(err)
+	}
+	fs.Var(&deprecatedFlag{Value: fs.Lookup(newName).Value, newName: newName},
+		oldName, "已废弃, 请使用 -"+newName)
+}
+
+// Defines a flag named name storing into p, which is a pointer to a
+// bool, int, int64, uint, uint64, float64, string or time.Duration, or
+// a flag.Value. The current value of p is the default.
+func defineVar(fs *flag.FlagSet, p interface{}, name, usage string) error {
+	switch p := p.(type) {
+	case *bool:
+		fs.BoolVar(p, name, *p, usage)
+	case *int:
+		fs.IntVar(p, name, *p, usage)
+	case *int64:
+		fs.Int64Var(p, name, *p, usage)
+	case *uint:
+		fs.UintVar(p, name, *p, usage)
+	case *uint64:
+		fs.Uint64Var(p, name, *p, usage)
+	case *float64:
+		fs.Float64Var(p, name, *p, usage)
+	case *string:
+		fs.StringVar(p, name, *p, usage)
+	case *time.Duration:
+		fs.DurationVar(p, name, *p, usage)
+	case flag.Value:
+		fs.Var(p, name, usage)
+	default:
+		return fmt.Errorf("选项 '%s' 的类型 %T 不支持", name, p)
+	}
+	return nil
+}
+
+// Warns about each deprecated flag name set in fs.
+func (c *Commands) warnDeprecatedFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if d, ok := f.Value.(*deprecatedFlag); ok {
+			c.warn("选项 -%s 已废弃, 请使用 -%s", f.Name, d.newName)
+		}
+	})
+}
+
+// Infers the type of a flag from the value returned by its Getter.
+func flagType(v flag.Value) string {
+	if g, ok := v.(flag.Getter); ok {
+		switch g.Get().(type) {
+		case bool:
+			return "bool"
+		case int:
+			return "int"
+		case int64:
+			return "int64"
+		case uint:
+			return "uint"
+		case uint64:
+			return "uint64"
+		case float64:
+			return "float64"
+		case string:
+			return "string"
+		case time.Duration:
+			return "duration"
+		}
+	}
+	if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return "bool"
+	}
+	return "value"
+}
@@ -0,0 +1,193 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAllFlagsCmd is a test sub command defining a flag of each type.
+type testAllFlagsCmd struct{}
+
+func (cmd *testAllFlagsCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.Bool("bool", false, "a bool")
+	fs.Int("int", 1, "an int")
+	fs.Int64("int64", 2, "an int64")
+	fs.Uint("uint", 3, "an uint")
+	fs.Uint64("uint64", 4, "an uint64")
+	fs.Float64("float64", 0.5, "a float64")
+	fs.String("string", "s", "a string")
+	fs.Duration("duration", time.Second, "a duration")
+	fs.Func("func", "a func", func(string) error { return nil })
+	return fs
+}
+
+func (cmd *testAllFlagsCmd) Run(args []string) error {
+	return nil
+}
+
+// Tests if the type of each standard flag is inferred.
+func TestFlagMetadata(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{"string"})
+
+	metas, err := c.FlagMetadata("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"bool":     "bool",
+		"int":      "int",
+		"int64":    "int64",
+		"uint":     "uint",
+		"uint64":   "uint64",
+		"float64":  "float64",
+		"string":   "string",
+		"duration": "duration",
+		"func":     "value",
+	}
+	if len(metas) != len(expected) {
+		t.Fatalf("expected %v flags, found %v", len(expected), len(metas))
+	}
+	for _, meta := range metas {
+		if meta.Type != expected[meta.Name] {
+			t.Errorf("flag %s: expected type %s, found %s", meta.Name, expected[meta.Name], meta.Type)
+		}
+		if meta.Required != (meta.Name == "string") {
+			t.Errorf("flag %s: unexpected required %v", meta.Name, meta.Required)
+		}
+	}
+	if metas[1].Name != "duration" || metas[1].Default != "1s" || metas[1].Usage != "a duration" {
+		t.Errorf("unexpected metadata of duration: %+v", metas[1])
+	}
+
+	if _, err := c.FlagMetadata("unknown"); err == nil {
+		t.Error("an unknown command is expected to fail")
+	}
+}
+
+// Tests if the inventory lists the commands defining each flag.
+func TestFlagInventory(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.flags.Bool("bool", false, "")
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	c.On("login", "", &testStringCmd{}, []string{})
+	c.On("logout", "", &testStringCmd{}, []string{})
+	c.OnForward("l", "", "login", nil)
+
+	inventory := c.FlagInventory()
+	if !reflect.DeepEqual(inventory["bool"], []string{"", "all"}) {
+		t.Errorf("expected bool to be defined globally and by all, found %q", inventory["bool"])
+	}
+	if !reflect.DeepEqual(inventory["token"], []string{"login", "logout"}) {
+		t.Errorf("expected token to be defined by login and logout, found %q", inventory["token"])
+	}
+	if !reflect.DeepEqual(inventory["int"], []string{"all"}) {
+		t.Errorf("expected int to be defined by all, found %q", inventory["int"])
+	}
+}
+
+// Tests if only the flags given on the command line are changed.
+func TestFlagChanged(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	if c.FlagChanged("bool") {
+		t.Error("no flag is expected to be changed before parsing")
+	}
+
+	c.Parse([]string{"all", "-bool=false", "-int", "1"})
+	for _, name := range []string{"bool", "int"} {
+		if !c.FlagChanged(name) {
+			t.Errorf("flag %s is expected to be changed", name)
+		}
+	}
+	for _, name := range []string{"string", "unknown"} {
+		if c.FlagChanged(name) {
+			t.Errorf("flag %s is not expected to be changed", name)
+		}
+	}
+}
+
+// testRenamedCmd is a test sub command whose -old flag was renamed to
+// -new.
+type testRenamedCmd struct {
+	value string
+	force bool
+}
+
+func (cmd *testRenamedCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	DeprecatedAliasVar(fs, &cmd.value, "old", "new", "Description about new")
+	DeprecatedAliasVar(fs, &cmd.force, "f", "force", "Description about force")
+	return fs
+}
+
+func (cmd *testRenamedCmd) Run(args []string) error {
+	return nil
+}
+
+// Tests if both names set the value and only the old one warns.
+func TestDeprecatedAliasVar(t *testing.T) {
+	var warnings bytes.Buffer
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testRenamedCmd{}
+	c.On("command1", "", cmd, []string{})
+	c.SetWarnOutput(&warnings)
+
+	c.Parse([]string{"command1", "-new", "a", "-force"})
+	if cmd.value != "a" || !cmd.force {
+		t.Errorf("expected a and true, found %s and %v", cmd.value, cmd.force)
+	}
+	if warnings.String() != "" {
+		t.Errorf("no warning is expected, found %q", warnings.String())
+	}
+
+	cmd.value, cmd.force = "", false
+	c.Parse([]string{"command1", "-old", "b", "-f", "arg"})
+	if cmd.value != "b" || !cmd.force {
+		t.Errorf("expected b and true, found %s and %v", cmd.value, cmd.force)
+	}
+	if !strings.Contains(warnings.String(), "选项 -old 已废弃, 请使用 -new") ||
+		!strings.Contains(warnings.String(), "选项 -f 已废弃, 请使用 -force") {
+		t.Errorf("expected warnings about -old and -f, found %q", warnings.String())
+	}
+	if len(c.args) != 1 || c.args[0] != "arg" {
+		t.Errorf("expected the argument 'arg', found %v", c.args)
+	}
+}
+
+// Tests if a renamed flag set by its deprecated name counts as set,
+// for the required flags, the constraints and FlagChanged.
+func TestDeprecatedAliasRequired(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.SetWarnOutput(io.Discard)
+	c.On("command1", "", &testRenamedCmd{}, []string{"new"})
+	c.MarkFlagsRequiredTogether("command1", "new", "force")
+
+	if err := c.ParseErr([]string{"command1", "-old", "a", "-f"}); err != nil {
+		t.Fatalf("expected -old to set the required -new, found %v", err)
+	}
+	if !c.FlagChanged("new") || !c.FlagChanged("force") {
+		t.Error("expected -new and -force to be changed")
+	}
+	if err := c.ParseErr([]string{"command1", "-old", "a"}); err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("expected -force to be required with -new, found %v", err)
+	}
+}
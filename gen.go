@@ -0,0 +1,191 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errWriter remembers the first error of the underlying writer and
+// skips all writes after it.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+// Calls fn for each registered sub-command in the order of
+// registration, a group before its sub-commands, stopping at the
+// first error.
+func (c *Commands) walk(fn func(subcmd *cmdInstance) error) error {
+	for _, subcmd := range c.list {
+		if err := fn(subcmd); err != nil {
+			return err
+		}
+		if subcmd.group != nil {
+			if err := subcmd.group.walk(fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Writes the sub-commands as a Graphviz DOT graph, with an edge from
+// the program to each sub-command and a dashed edge from a forwarding
+// sub-command to its target. The flags of a sub-command are listed in
+// the tooltip of its node. Those refused by the authorizer are left
+// out.
+func (c *Commands) GenDOT(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("digraph %s {\n", strconv.Quote(c.program))
+	ew.printf("\t%s [shape=box];\n", strconv.Quote(c.program))
+	err := c.walk(func(subcmd *cmdInstance) error {
+		if !c.authorized(subcmd) {
+			return nil
+		}
+		id := strconv.Quote(c.program + " " + subcmd.pathName())
+		var flags []string
+		if fs, _, err := c.commandFlags(subcmd.pathName()); err == nil {
+			fs.VisitAll(func(f *flag.Flag) {
+				flags = append(flags, "-"+f.Name+": "+f.Usage)
+			})
+		}
+		flags = append(flags, annotationLines(subcmd.annotations)...)
+		ew.printf("\t%s [label=%s, tooltip=%s];\n", id, strconv.Quote(subcmd.name),
+			strconv.Quote(strings.Join(append([]string{subcmd.description}, flags...), "\n")))
+		parent := c.program
+		if subcmd.parent != nil {
+			parent += " " + subcmd.parent.pathName()
+		}
+		ew.printf("\t%s -> %s;\n", strconv.Quote(parent), id)
+		if subcmd.forward != "" {
+			ew.printf("\t%s -> %s [style=dashed];\n", id, strconv.Quote(parent+" "+subcmd.forward))
+		}
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+// Writes the program and the sub-commands as an outline, each
+// sub-command indented by two spaces per level of its path and followed
+// by its description. Those refused by the authorizer are left out.
+func (c *Commands) GenOutline(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("%s\n", c.program)
+	err := c.walk(func(subcmd *cmdInstance) error {
+		if !c.authorized(subcmd) {
+			return nil
+		}
+		indent := strings.Repeat("  ", len(subcmd.path()))
+		if subcmd.description == "" {
+			ew.printf("%s%s\n", indent, subcmd.name)
+		} else {
+			ew.printf("%s%-15s %s\n", indent, subcmd.name, subcmd.description)
+		}
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+	return ew.err
+}
+
+// Returns the annotations as "key: value" lines sorted by key.
+func annotationLines(annotations map[string]string) []string {
+	var lines []string
+	for key, value := range annotations {
+		lines = append(lines, key+": "+value)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// flagJSON describes a flag in the output of DumpJSON.
+type flagJSON struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Default  string `json:"default,omitempty"`
+	Usage    string `json:"usage,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	// The default is redacted.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// commandJSON describes a sub-command in the output of DumpJSON.
+type commandJSON struct {
+	Name        string            `json:"name"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Path        []string          `json:"path"`
+	Forward     string            `json:"forward,omitempty"`
+	Categories  []string          `json:"categories,omitempty"`
+	Flags       []flagJSON        `json:"flags,omitempty"`
+	Examples    []string          `json:"examples,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Writes the sub-commands, including those of the groups, as an
+// indented JSON document, with the program name, e.g. for tools
+// building on the command line. Those refused by the authorizer are
+// left out.
+func (c *Commands) DumpJSON(w io.Writer) error {
+	doc := struct {
+		Program  string        `json:"program"`
+		Commands []commandJSON `json:"commands"`
+	}{Program: c.program, Commands: []commandJSON{}}
+	c.walk(func(subcmd *cmdInstance) error {
+		if !c.authorized(subcmd) {
+			return nil
+		}
+		cmd := commandJSON{
+			Name:        subcmd.name,
+			Aliases:     subcmd.aliases,
+			Description: subcmd.description,
+			Path:        subcmd.path(),
+			Forward:     subcmd.forward,
+			Categories:  subcmd.categories,
+			Examples:    subcmd.examples,
+			Annotations: subcmd.annotations,
+		}
+		metas, _ := c.FlagMetadata(subcmd.pathName())
+		for _, meta := range metas {
+			cmd.Flags = append(cmd.Flags, flagJSON(meta))
+		}
+		doc.Commands = append(doc.Commands, cmd)
+		return nil
+	})
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
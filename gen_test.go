@@ -0,0 +1,199 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests if the commands are written as a DOT graph.
+func TestGenDOT(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.OnForward("logs", "description of logs", "command1", []string{"-flag1"})
+
+	var buf bytes.Buffer
+	if err := c.GenDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph "cmd" {
+	"cmd" [shape=box];
+	"cmd command1" [label="command1", tooltip="description of command1\n-flag1: Description about flag1"];
+	"cmd" -> "cmd command1";
+	"cmd logs" [label="logs", tooltip="description of logs\n-flag1: Description about flag1"];
+	"cmd" -> "cmd logs";
+	"cmd logs" -> "cmd command1" [style=dashed];
+}
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nfound\n%s", expected, buf.String())
+	}
+}
+
+// Tests if the sub-commands are indented under the program, without
+// those refused by the authorizer.
+func TestGenOutline(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+	c.On("secret", "", &testCmd2{}, []string{})
+	c.SetAuthorizer(func(name string) bool { return name != "secret" })
+
+	var buf bytes.Buffer
+	if err := c.GenOutline(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "cmd\n  command1        description of command1\n  command2\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nfound\n%s", expected, buf.String())
+	}
+}
+
+// Tests if the annotations are listed and written by DumpJSON.
+func TestAnnotations(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{"flag1"})
+	c.On("command2", "", &testCmd2{}, []string{})
+	c.Annotate("command1", "stability", "beta")
+	c.Annotate("command1", "owner", "storage")
+	expected := map[string]string{"stability": "beta", "owner": "storage"}
+
+	infos := c.List()
+	if !reflect.DeepEqual(infos[0].Annotations, expected) || infos[1].Annotations != nil {
+		t.Errorf("unexpected annotations %v and %v", infos[0].Annotations, infos[1].Annotations)
+	}
+
+	var buf bytes.Buffer
+	if err := c.DumpJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Program  string
+		Commands []struct {
+			Name        string
+			Flags       []FlagMeta
+			Annotations map[string]string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Program != "cmd" || len(doc.Commands) != 2 || doc.Commands[0].Name != "command1" {
+		t.Fatalf("unexpected document %s", buf.String())
+	}
+	if !reflect.DeepEqual(doc.Commands[0].Annotations, expected) {
+		t.Errorf("expected the annotations %v, found %v", expected, doc.Commands[0].Annotations)
+	}
+	if flags := doc.Commands[0].Flags; len(flags) != 1 || flags[0].Name != "flag1" || !flags[0].Required {
+		t.Errorf("expected the required flag1, found %+v", flags)
+	}
+
+	buf.Reset()
+	if err := c.GenDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `\nowner: storage\nstability: beta"`) {
+		t.Errorf("expected the annotations in the tooltip, found %s", buf.String())
+	}
+}
+
+// Tests if the sub-commands of the groups are dumped and graphed with
+// their flags, without those refused by the authorizer.
+func TestGenGroups(t *testing.T) {
+	c, _ := newGroupCommands()
+	c.Annotate("remote add", "owner", "storage")
+	c.SetAuthorizer(func(name string) bool { return name != "remote show" })
+
+	var buf bytes.Buffer
+	if err := c.DumpJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Commands []struct {
+			Path        []string
+			Flags       []FlagMeta
+			Annotations map[string]string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, cmd := range doc.Commands {
+		paths = append(paths, strings.Join(cmd.Path, " "))
+	}
+	if expected := []string{"command1", "remote", "remote add", "remote a"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected the commands %q, found %q", expected, paths)
+	}
+	add := doc.Commands[2]
+	if len(add.Flags) != 1 || add.Flags[0].Name != "token" || !add.Flags[0].Required || add.Annotations["owner"] != "storage" {
+		t.Errorf("expected the metadata of remote add, found %+v", add)
+	}
+
+	buf.Reset()
+	if err := c.GenDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"cmd remote" -> "cmd remote add";`) || strings.Contains(buf.String(), "remote show") {
+		t.Errorf("expected the authorized commands of the group, found %s", buf.String())
+	}
+}
+
+// failingWriter fails every write after the first n, like a closed
+// pipe.
+type failingWriter struct {
+	n      int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > w.n {
+		return 0, io.ErrClosedPipe
+	}
+	return len(p), nil
+}
+
+// Tests if the generators stop at the first failing write and return
+// its error.
+func TestGenWriteError(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.On("command2", "", &testCmd2{}, []string{})
+
+	for name, gen := range map[string]func(w *failingWriter) error{
+		"GenDOT":     func(w *failingWriter) error { return c.GenDOT(w) },
+		"GenOutline": func(w *failingWriter) error { return c.GenOutline(w) },
+		"DumpJSON":   func(w *failingWriter) error { return c.DumpJSON(w) },
+	} {
+		w := &failingWriter{n: 1}
+		if name == "DumpJSON" {
+			w.n = 0
+		}
+		if err := gen(w); !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("%s: expected the closed pipe, found %v", name, err)
+		}
+		if w.writes != w.n+1 {
+			t.Errorf("%s: expected to stop after the failing write, found %d writes", name, w.writes)
+		}
+	}
+}
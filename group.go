@@ -0,0 +1,87 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"strings"
+)
+
+// Registers a group of sub-commands, e.g. remote, and returns the
+// Commands to register its sub-commands on, e.g. add to run as
+// `prog remote add`. Groups can be nested. The sub-commands of a group
+// are parsed and run with the settings of c, e.g. its authorizer;
+// only the sub-commands and their own settings, e.g. their examples,
+// are taken from the returned Commands. Elsewhere, e.g. in
+// FlagMetadata, they are named by their path, e.g. "remote add".
+func (c *Commands) OnGroup(name, description string) *Commands {
+	group := &Commands{program: c.program, flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	c.On(name, description, nil, nil)
+	subcmd := c.list[len(c.list)-1]
+	subcmd.command = &groupCmd{subcmd: subcmd}
+	subcmd.group = group
+	group.parent = subcmd
+	return group
+}
+
+// groupCmd is the command of a group registered by OnGroup, whose usage
+// lists the sub-commands of the group.
+type groupCmd struct {
+	subcmd *cmdInstance
+}
+
+func (cmd *groupCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+// Only runs through a forwarding sub-command, as Parse descends into
+// the group otherwise.
+func (cmd *groupCmd) Run(args []string) error {
+	return ErrNoSubcommand
+}
+
+// Returns the Commands subcmd is registered on, c or a group.
+func (c *Commands) owner(subcmd *cmdInstance) *Commands {
+	if subcmd.parent != nil {
+		return subcmd.parent.group
+	}
+	return c
+}
+
+// Returns the sub-command with the given path, e.g. "remote add" for
+// add in the remote group, or nil if there is none.
+func (c *Commands) find(path string) *cmdInstance {
+	names := strings.Fields(path)
+	if len(names) == 0 {
+		return nil
+	}
+	subcmd := c.lookup(names[0])
+	for _, name := range names[1:] {
+		if subcmd == nil || subcmd.group == nil {
+			return nil
+		}
+		subcmd = subcmd.group.lookup(name)
+	}
+	return subcmd
+}
+
+// Reports whether arg asks for help, like the -h flag of a sub-command.
+func isHelpFlag(arg string) bool {
+	switch arg {
+	case "-h", "-?", "-help", "--help":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns commands with the remote group holding add and show, and
+// a top-level command1.
+func newGroupCommands() (*Commands, *testStringCmd) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	remote := c.OnGroup("remote", "manage remotes")
+	add := &testStringCmd{}
+	remote.On("add", "add a remote", add, []string{"token"})
+	remote.On("show", "show a remote", &testCmd1{}, []string{})
+	remote.OnForward("a", "", "add", nil)
+	return c, add
+}
+
+// Tests if the args are matched through the groups to the leaf.
+func TestGroupParse(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+	c, add := newGroupCommands()
+
+	c.ParseAndRun([]string{"remote", "add", "-token", "x", "origin"})
+	if *code != -100 || c.matchingCmd.name != "add" || *add.token != "x" {
+		t.Fatalf("expected add to run with the token, found %v %d", c.matchingCmd, *code)
+	}
+	if !reflect.DeepEqual(c.args, []string{"origin"}) {
+		t.Errorf("expected the argument origin, found %q", c.args)
+	}
+	if err := c.ParseErr([]string{"remote", "a", "-token", "y"}); err != nil || c.matchingTarget.name != "add" {
+		t.Errorf("expected a to forward to add, found %v", err)
+	}
+
+	err := c.ParseErr([]string{"remote", "add"})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Command != "add" || !strings.Contains(err.Error(), "-token") {
+		t.Errorf("expected the token to be required by add, found %v", err)
+	}
+	if err := c.ParseErr([]string{"remote", "ad"}); !errors.Is(err, ErrUnknownSubcommand) {
+		t.Errorf("expected an unknown command, found %v", err)
+	}
+	if err := c.ParseErr([]string{"remote"}); !errors.Is(err, ErrNoSubcommand) {
+		t.Errorf("expected a missing command, found %v", err)
+	}
+
+	stderr.Reset()
+	c.ParseAndRun([]string{"remote", "add", "-h"})
+	if !strings.Contains(stderr.String(), "使用方法: cmd remote add [选项]") {
+		t.Errorf("expected the usage of add, found %q", stderr.String())
+	}
+	stderr.Reset()
+	c.ParseAndRun([]string{"remote", "-h"})
+	for _, s := range []string{"manage remotes", "使用方法: cmd remote 子命令 [选项]", "add             add a remote"} {
+		if !strings.Contains(stderr.String(), s) {
+			t.Errorf("expected %q in the usage of the group, found %q", s, stderr.String())
+		}
+	}
+}
+
+// Tests if the commands of the groups are named by their paths.
+func TestGroupPaths(t *testing.T) {
+	c, _ := newGroupCommands()
+	c.SetExamples("remote add", "cmd remote add -token x origin")
+	if examples := c.find("remote add").examples; len(examples) != 1 {
+		t.Errorf("expected the example of add, found %q", examples)
+	}
+	if required := c.RequiredFlags("remote add"); !reflect.DeepEqual(required, []string{"token"}) {
+		t.Errorf("expected the token to be required, found %q", required)
+	}
+	if c.find("remote missing") != nil || c.find("command1 add") != nil {
+		t.Error("no command is expected for an unknown path")
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("no problem is expected, found %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.GenOutline(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "cmd\n" +
+		"  command1        description of command1\n" +
+		"  remote          manage remotes\n" +
+		"    add             add a remote\n" +
+		"    show            show a remote\n" +
+		"    a\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nfound\n%s", expected, buf.String())
+	}
+
+	c.SetAuthorizer(func(name string) bool { return name != "remote" })
+	if err := c.ParseErr([]string{"remote", "show"}); err == nil {
+		t.Error("the commands of a refused group are expected to be refused")
+	}
+}
+
+// Tests if the usage of a group lists the authorized sub-commands
+// through the renderer, and if an unknown sub-command of a group points
+// to the usage of the group.
+func TestGroupUsage(t *testing.T) {
+	stderr := captureStdErr(t)
+	captureExit(t)
+	c, _ := newGroupCommands()
+	c.SetAuthorizer(func(name string) bool { return name != "remote show" })
+
+	c.ParseAndRun([]string{"remote", "-h"})
+	if !strings.Contains(stderr.String(), "add a remote") || strings.Contains(stderr.String(), "show a remote") {
+		t.Errorf("expected only the authorized commands, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	c.Parse([]string{"remote", "ad"})
+	if !strings.Contains(stderr.String(), `未知的子命令: "ad"`) || !strings.Contains(stderr.String(), "使用方法: cmd remote 子命令 [选项]") {
+		t.Errorf("expected the usage of the group, found %q", stderr.String())
+	}
+	stderr.Reset()
+	c.SetSuppressUsageOnError(true)
+	c.Parse([]string{"remote", "ad"})
+	if !strings.Contains(stderr.String(), "运行 'cmd remote -h' 查看使用方法。") {
+		t.Errorf("expected the hint of the group, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	c.SetHelpRenderer(testRenderer{})
+	c.ParseAndRun([]string{"remote", "-h"})
+	if stderr.String() != "usage cmd remote\nflag ?\nflag h\nflag help\n" {
+		t.Errorf("expected the custom usage, found %q", stderr.String())
+	}
+}
+
+// Tests if a group refused by the authorizer is neither described nor
+// descended into.
+func TestGroupRefused(t *testing.T) {
+	for _, args := range [][]string{{"remote", "-h"}, {"remote"}, {"remote", "ad"}, {"remote", "add", "-token", "x"}} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+		c, add := newGroupCommands()
+		c.SetAuthorizer(func(name string) bool { return name != "remote" })
+
+		c.ParseAndRun(args)
+		if *code != ExitNoPermission || add.run {
+			t.Errorf("%q: expected exit code %d without running, found %d", args, ExitNoPermission, *code)
+		}
+		if !strings.Contains(stderr.String(), "命令 'remote' 不可用") || strings.Contains(stderr.String(), "manage remotes") {
+			t.Errorf("%q: expected the group to be refused without its usage, found %q", args, stderr.String())
+		}
+	}
+}
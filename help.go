@@ -0,0 +1,111 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"strings"
+)
+
+// The levels of detail of the usage.
+const (
+	// Only the synopsis and the names of the flags.
+	HelpSummary = "summary"
+	// The descriptions and defaults of the flags, the constraints and
+	// the examples too, the default.
+	HelpFull = "full"
+)
+
+// Sets how much the usage shows, HelpSummary or HelpFull.
+func (c *Commands) SetHelpLevel(level string) {
+	c.helpLevel = level
+}
+
+// Defines a global flag named name selecting the help level, see
+// SetHelpLevel.
+func (c *Commands) EnableHelpLevelFlag(name string) {
+	if c.helpLevel == "" {
+		c.helpLevel = HelpFull
+	}
+	c.flags.StringVar(&c.helpLevel, name, c.helpLevel, "帮助的详细程度: summary 或 full")
+}
+
+// The verbosity levels of the usage.
+const (
+	// The names and descriptions of the sub-commands.
+	UsageNames = iota
+	// The flags too.
+	UsageFlags
+	// The constraints and examples too, the default.
+	UsageFull
+)
+
+// Sets the verbosity of the usage, UsageNames, UsageFlags or UsageFull.
+func (c *Commands) SetUsageVerbosity(level int) {
+	c.usageVerbosity = level
+}
+
+// Defines a global flag named name selecting the verbosity of the
+// usage, see SetUsageVerbosity.
+func (c *Commands) EnableUsageVerbosityFlag(name string) {
+	c.flags.IntVar(&c.usageVerbosity, name, c.usageVerbosity, "帮助的详细程度: 0 只显示名称, 1 包括选项, 2 包括约束和示例")
+}
+
+// Sets the arguments, e.g. help, usage and ?, which print the usage of
+// a sub-command instead of running it when given as its only argument.
+// There are none by default.
+func (c *Commands) SetHelpTokens(tokens []string) {
+	c.helpTokens = tokens
+}
+
+// Sets the usage of the -h flag added to every sub-command, -? and
+// -help are described as the same as -h.
+func (c *Commands) SetHelpFlagUsage(s string) {
+	c.helpUsage = s
+}
+
+// Defines the help flags of a sub-command in fs, bound to p.
+func (c *Commands) helpFlags(fs *flag.FlagSet, p *bool) {
+	fs.BoolVar(p, "h", false, c.helpFlagUsage())
+	fs.BoolVar(p, "?", false, "同 -h")
+	fs.BoolVar(p, "help", false, "同 -h")
+}
+
+// Returns the usage of the -h flag.
+func (c *Commands) helpFlagUsage() string {
+	if c.helpUsage != "" {
+		return c.helpUsage
+	}
+	return "显示子命令的帮助信息"
+}
+
+// Reports whether arg is one of the help tokens.
+func (c *Commands) isHelpToken(arg string) bool {
+	for _, token := range c.helpTokens {
+		if arg == token {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the names of the flags in fs, e.g. "-a -b".
+func flagNames(fs *flag.FlagSet) string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return strings.Join(names, " ")
+}
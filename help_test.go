@@ -0,0 +1,163 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests if the help level flag selects the detail of the usage.
+func TestHelpLevel(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "description of command1", &testCmd1{}, []string{})
+	c.SetExamples("command1", "cmd command1 -flag1")
+	c.EnableHelpLevelFlag("help-level")
+
+	c.SubcommandUsage(c.lookup("command1"))
+	full := stderr.String()
+	for _, s := range []string{"description of command1", "Description about flag1", "cmd command1 -flag1"} {
+		if !strings.Contains(full, s) {
+			t.Errorf("the full usage is expected to contain %q, found %q", s, full)
+		}
+	}
+
+	if err := c.flags.Parse([]string{"-help-level=summary"}); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	c.SubcommandUsage(c.lookup("command1"))
+	expected := "使用方法: cmd command1 [选项]\n选项: -? -flag1 -h -help\n"
+	if stderr.String() != expected {
+		t.Errorf("expected the summary %q, found %q", expected, stderr.String())
+	}
+
+	stderr.Reset()
+	c.Usage()
+	if !strings.Contains(stderr.String(), "  -help-level\n") {
+		t.Errorf("expected only the names of the global flags, found %q", stderr.String())
+	}
+}
+
+// Tests if the usage verbosity selects what the usage shows.
+func TestUsageVerbosity(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "all kinds of flags", &testAllFlagsCmd{}, []string{})
+	c.MarkFlagsMutuallyExclusive("all", "int", "int64")
+	c.SetExamples("all", "cmd all -int 1")
+	c.EnableUsageVerbosityFlag("usage-level")
+
+	for _, test := range []struct {
+		level    string
+		contains []string
+		excludes []string
+	}{
+		{"0", []string{"all kinds of flags", "使用方法: cmd all"}, []string{"an int64", "约束:", "示例:"}},
+		{"1", []string{"all kinds of flags", "an int64"}, []string{"约束:", "示例:"}},
+		{"2", []string{"all kinds of flags", "an int64", "约束:", "示例:"}, nil},
+	} {
+		if err := c.flags.Parse([]string{"-usage-level", test.level}); err != nil {
+			t.Fatal(err)
+		}
+		stderr.Reset()
+		c.SubcommandUsage(c.lookup("all"))
+		for _, s := range test.contains {
+			if !strings.Contains(stderr.String(), s) {
+				t.Errorf("level %s: expected %q, found %q", test.level, s, stderr.String())
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(stderr.String(), s) {
+				t.Errorf("level %s: %q is not expected, found %q", test.level, s, stderr.String())
+			}
+		}
+
+		stderr.Reset()
+		c.Usage()
+		if hasFlags := strings.Contains(stderr.String(), "-usage-level int"); hasFlags != (test.level != "0") {
+			t.Errorf("level %s: unexpected global flags in %q", test.level, stderr.String())
+		}
+	}
+}
+
+// Tests if a help token given as the only argument prints the usage.
+func TestHelpTokens(t *testing.T) {
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testCmd1{}
+	c.On("command1", "description of command1", cmd, []string{})
+
+	c.ParseAndRun([]string{"command1", "?"})
+	if !cmd.run || stderr.String() != "" {
+		t.Errorf("expected ? to be an argument by default, found %q", stderr.String())
+	}
+
+	c.SetHelpTokens([]string{"help", "usage", "?"})
+	for _, args := range [][]string{{"command1", "?"}, {"command1", "-flag1", "usage"}} {
+		cmd.run = false
+		stderr.Reset()
+		c.ParseAndRun(args)
+		if cmd.run || !strings.Contains(stderr.String(), "description of command1") {
+			t.Errorf("%v: expected the usage instead of running, found %q", args, stderr.String())
+		}
+	}
+
+	cmd.run = false
+	c.ParseAndRun([]string{"command1", "help", "me"})
+	if !cmd.run || !reflect.DeepEqual(c.args, []string{"help", "me"}) {
+		t.Errorf("expected to run with the arguments, found %v", c.args)
+	}
+}
+
+// Tests if the help flags are described, both when parsing and in the
+// usage of a sub-command.
+func TestHelpFlagUsage(t *testing.T) {
+	stderr := captureStdErr(t)
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+
+	for _, test := range []struct {
+		usage    string
+		expected string
+	}{
+		{"", "显示子命令的帮助信息"},
+		{"show help for this command", "show help for this command"},
+	} {
+		c.SetHelpFlagUsage(test.usage)
+		c.Parse([]string{"command1"})
+		var buf bytes.Buffer
+		c.matchingFlagSet.SetOutput(&buf)
+		c.matchingFlagSet.PrintDefaults()
+		stderr.Reset()
+		c.SubcommandUsage(c.lookup("command1"))
+		for _, s := range []string{"  -h\t" + test.expected, "  -help\n    \t同 -h", "  -?\t同 -h"} {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("expected %q in the flags, found %q", s, buf.String())
+			}
+			if !strings.Contains(stderr.String(), s) {
+				t.Errorf("expected %q in the usage, found %q", s, stderr.String())
+			}
+		}
+	}
+}
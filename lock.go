@@ -0,0 +1,77 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errLocked is returned by lockFile if the lock is held by another
+// process.
+var errLocked = errors.New("locked")
+
+// Requires the named sub-command to hold a lock while it runs, so that
+// it never runs concurrently with itself, e.g. a migration. If another
+// instance holds the lock, Run fails right away. The lock is a file in
+// the directory set by SetLockDir, by default a directory named after
+// the program in the cache directory of the user, see os.UserCacheDir,
+// or in the temporary directory if there is none. The lock file is
+// never followed if it is a symbolic link.
+func (c *Commands) RequireLock(name string) {
+	c.mustLookup(name).requireLock = true
+}
+
+// Sets the directory of the lock files of the sub-commands requiring a
+// lock.
+func (c *Commands) SetLockDir(dir string) {
+	c.lockDir = dir
+}
+
+// Returns the path of the lock file of subcmd.
+func (c *Commands) lockPath(subcmd *cmdInstance) string {
+	dir := c.lockDir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			cache = os.TempDir()
+		}
+		dir = filepath.Join(cache, filepath.Base(c.program))
+	}
+	return filepath.Join(dir, filepath.Base(c.program)+"-"+strings.Join(subcmd.path(), "-")+".lock")
+}
+
+// Acquires the lock of subcmd if it requires one, returning the
+// function releasing it.
+func (c *Commands) lock(subcmd *cmdInstance) (func(), error) {
+	if !subcmd.requireLock {
+		return func() {}, nil
+	}
+	path := c.lockPath(subcmd)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("无法锁定 '%s': %s", path, err)
+	}
+	unlock, err := lockFile(path)
+	if err == errLocked {
+		return nil, fmt.Errorf("命令 '%s' 的另一个实例正在运行", subcmd.name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法锁定 '%s': %s", path, err)
+	}
+	return unlock, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests if a command requiring a lock fails while another instance
+// holds it.
+func TestRequireLock(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("migrate", "", c1, []string{})
+	c.RequireLock("migrate")
+	c.SetLockDir(t.TempDir())
+
+	unlock, err := c.lock(c.lookup("migrate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ParseAndRun([]string{"migrate"})
+	if c1.run || *code != 1 || !strings.Contains(stderr.String(), "另一个实例正在运行") {
+		t.Errorf("expected migrate to fail while locked, found run %v, exit code %v: %q", c1.run, *code, stderr.String())
+	}
+
+	unlock()
+	*code = -100
+	c.ParseAndRun([]string{"migrate"})
+	if !c1.run || *code != -100 {
+		t.Errorf("expected migrate to run once unlocked, found run %v, exit code %v", c1.run, *code)
+	}
+
+	// released after running
+	unlock, err = c.lock(c.lookup("migrate"))
+	if err != nil {
+		t.Fatalf("the lock is expected to be released after running, found %v", err)
+	}
+	unlock()
+}
+
+// Tests if the lock files are kept per user by default, and if a lock
+// file which is a symbolic link is refused.
+func TestLockPath(t *testing.T) {
+	c := New("/usr/bin/cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("migrate", "", &testCmd1{}, []string{})
+	c.RequireLock("migrate")
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	if path := c.lockPath(c.lookup("migrate")); path != filepath.Join(cache, "cmd", "cmd-migrate.lock") {
+		t.Errorf("expected the lock file in the cache directory, found %s", path)
+	}
+
+	dir := t.TempDir()
+	c.SetLockDir(dir)
+	target := filepath.Join(dir, "target")
+	if err := os.Symlink(target, c.lockPath(c.lookup("migrate"))); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+	if unlock, err := c.lock(c.lookup("migrate")); err == nil {
+		unlock()
+		t.Error("a symbolic link is not expected to be locked")
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("the target of the link is not expected to be created, found %v", err)
+	}
+}
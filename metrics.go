@@ -0,0 +1,88 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics is a registry of counters and timers sub-commands record
+// while they run, flushed to the sink set by SetMetricsSink once the
+// sub-command completes. It is safe for concurrent use.
+type Metrics struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Increments the counter named name by one.
+func (m *Metrics) Inc(name string) {
+	m.Add(name, 1)
+}
+
+// Adds delta to the counter named name.
+func (m *Metrics) Add(name string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values == nil {
+		m.values = make(map[string]float64)
+	}
+	m.values[name] += delta
+}
+
+// Starts a timer, the returned function adds the seconds elapsed since
+// to the metric named name:
+//
+//	defer c.Metrics().Time("upload_seconds")()
+func (m *Metrics) Time(name string) func() {
+	start := time.Now()
+	return func() {
+		m.Add(name, time.Since(start).Seconds())
+	}
+}
+
+// Returns the recorded values and clears them.
+func (m *Metrics) flush() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := m.values
+	m.values = nil
+	return values
+}
+
+// Returns the metrics registry of the sub-commands.
+func (c *Commands) Metrics() *Metrics {
+	if c.metrics == nil {
+		c.metrics = &Metrics{}
+	}
+	return c.metrics
+}
+
+// Sets the sink the metrics are flushed to after the sub-command
+// runs, whether it succeeds or not, e.g. to write them to statsd or
+// a prometheus textfile. It isn't called if nothing was recorded.
+func (c *Commands) SetMetricsSink(sink func(values map[string]float64)) {
+	c.metricsSink = sink
+}
+
+// Flushes the recorded metrics to the sink.
+func (c *Commands) flushMetrics() {
+	if c.metricsSink == nil || c.metrics == nil {
+		return
+	}
+	if values := c.metrics.flush(); len(values) > 0 {
+		c.metricsSink(values)
+	}
+}
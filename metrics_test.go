@@ -0,0 +1,66 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"testing"
+)
+
+// testMetricsCmd is a test sub command recording metrics.
+type testMetricsCmd struct {
+	c   *Commands
+	err error
+}
+
+func (cmd *testMetricsCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testMetricsCmd) Run(args []string) error {
+	defer cmd.c.Metrics().Time("seconds")()
+	for range args {
+		cmd.c.Metrics().Inc("items")
+	}
+	return cmd.err
+}
+
+// Tests if the metrics are flushed to the sink after the command runs.
+func TestMetrics(t *testing.T) {
+	captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testMetricsCmd{c: c}
+	c.On("process", "", cmd, []string{})
+	var flushed []map[string]float64
+	c.SetMetricsSink(func(values map[string]float64) {
+		flushed = append(flushed, values)
+	})
+
+	c.ParseAndRun([]string{"process", "a", "b", "c"})
+	cmd.err = errors.New("failed")
+	c.ParseAndRun([]string{"process", "d"})
+	if len(flushed) != 2 {
+		t.Fatalf("expected the metrics to be flushed twice, found %v", len(flushed))
+	}
+	if flushed[0]["items"] != 3 || flushed[1]["items"] != 1 {
+		t.Errorf("expected 3 then 1 items, found %v", flushed)
+	}
+	if _, ok := flushed[1]["seconds"]; !ok {
+		t.Errorf("expected the timer to be recorded, found %v", flushed[1])
+	}
+}
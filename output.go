@@ -0,0 +1,83 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+// OutputCmd is a sub command returning its result instead of printing
+// it, the result is printed by the package in the output format.
+type OutputCmd interface {
+	Flags(*flag.FlagSet) *flag.FlagSet
+	RunOutput(args []string) (interface{}, error)
+}
+
+// Registers an OutputCmd for the provided sub-command name, a non-nil
+// result of the command is printed to StdOutput in the output format.
+func (c *Commands) OnOutput(name, description string, command OutputCmd, requiredFlags []string) {
+	c.On(name, description, &outputCmd{c: c, command: command}, requiredFlags)
+}
+
+// Sets the format results of OutputCmds are printed in, either text
+// (the default) which prints them as with fmt.Println, or json.
+func (c *Commands) SetOutputFormat(format string) {
+	c.outputFormat = format
+}
+
+// Defines a global flag named name selecting the output format, see
+// SetOutputFormat.
+func (c *Commands) EnableOutputFlag(name string) {
+	if c.outputFormat == "" {
+		c.outputFormat = "text"
+	}
+	c.flags.StringVar(&c.outputFormat, name, c.outputFormat, "输出格式: text 或 json")
+}
+
+// outputCmd adapts an OutputCmd to a Cmd.
+type outputCmd struct {
+	c       *Commands
+	command OutputCmd
+}
+
+func (cmd *outputCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return cmd.command.Flags(fs)
+}
+
+func (cmd *outputCmd) Run(args []string) error {
+	// checked first, the command mustn't run for nothing
+	switch cmd.c.outputFormat {
+	case "", "text", "json":
+	default:
+		return &Error{Code: cmd.c.usageCode(), Message: fmt.Sprintf("不支持的输出格式: %s", cmd.c.outputFormat)}
+	}
+
+	v, err := cmd.command.RunOutput(args)
+	if err != nil || v == nil {
+		return err
+	}
+	if cmd.c.outputFormat == "json" {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		Println(string(data))
+		return nil
+	}
+	Println(v)
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testStatus struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// testOutputCmd is a test sub command returning a status.
+type testOutputCmd struct{}
+
+func (cmd *testOutputCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testOutputCmd) RunOutput(args []string) (interface{}, error) {
+	return testStatus{Name: "web", Ready: true}, nil
+}
+
+// Tests if the result of an OutputCmd is printed in the output format.
+func TestOutputCmd(t *testing.T) {
+	stdout := captureStdOutput(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.OnOutput("status", "", &testOutputCmd{}, []string{})
+	c.ParseAndRun([]string{"status"})
+	if stdout.String() != "{web true}\n" {
+		t.Errorf("expected the status as text, found %q", stdout.String())
+	}
+
+	stdout.Reset()
+	c.EnableOutputFlag("output")
+	if err := c.flags.Parse([]string{"-output", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	c.ParseAndRun([]string{"status"})
+	expected := "{\n  \"name\": \"web\",\n  \"ready\": true\n}\n"
+	if stdout.String() != expected {
+		t.Errorf("expected the status as json %q, found %q", expected, stdout.String())
+	}
+}
+
+// testRanOutputCmd is a test sub command recording whether it ran.
+type testRanOutputCmd struct {
+	testOutputCmd
+	ran bool
+}
+
+func (cmd *testRanOutputCmd) RunOutput(args []string) (interface{}, error) {
+	cmd.ran = true
+	return cmd.testOutputCmd.RunOutput(args)
+}
+
+// Tests if an unsupported output format fails before the command runs.
+func TestOutputFormatInvalid(t *testing.T) {
+	stdout := captureStdOutput(t)
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testRanOutputCmd{}
+	c.OnOutput("status", "", cmd, []string{})
+	c.SetOutputFormat("yaml")
+	c.ParseAndRun([]string{"status"})
+	if cmd.ran || *code != 1 || stdout.Len() != 0 {
+		t.Errorf("expected to fail without running, found run %v, exit code %v", cmd.ran, *code)
+	}
+	if !strings.Contains(stderr.String(), "不支持的输出格式: yaml") {
+		t.Errorf("expected the unsupported format, found %q", stderr.String())
+	}
+}
+
+// Tests if a command outputs to the writers resolved for it.
+func TestOutputResolver(t *testing.T) {
+	stdout := captureStdOutput(t)
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	var statusOut, failErr bytes.Buffer
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.OnOutput("status", "", &testOutputCmd{}, []string{})
+	c.On("fail", "", &testErrCmd{err: errors.New("boom")}, []string{})
+	c.SetOutputResolver(func(cmdName string) (io.Writer, io.Writer) {
+		if cmdName == "status" {
+			return &statusOut, nil
+		}
+		return nil, &failErr
+	})
+
+	c.ParseAndRun([]string{"status"})
+	c.ParseAndRun([]string{"fail"})
+	if statusOut.String() != "{web true}\n" || stdout.String() != "" {
+		t.Errorf("expected the status in its own output, found %q and %q", statusOut.String(), stdout.String())
+	}
+	if !strings.Contains(failErr.String(), "boom") || stderr.String() != "" {
+		t.Errorf("expected the error in its own output, found %q and %q", failErr.String(), stderr.String())
+	}
+	if StdOutput != stdout || StdErr != stderr {
+		t.Error("the writers are expected to be restored")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// Loads the Go plugin at path, built with -buildmode=plugin, and calls
+// the function it exports as RegisterCommands to register its
+// sub-commands:
+//
+//	func RegisterCommands(c *command.Commands) {
+//		c.On("hello", "says hello", &helloCmd{}, nil)
+//	}
+//
+// If the plugin registers a sub-command which already exists, none of
+// its sub-commands are kept and an error is returned. Plugins are only
+// supported on some platforms, see the plugin package.
+func (c *Commands) LoadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("加载插件 '%s' 失败: %s", path, err)
+	}
+	sym, err := p.Lookup("RegisterCommands")
+	if err != nil {
+		return fmt.Errorf("插件 '%s' 没有导出 RegisterCommands: %s", path, err)
+	}
+	register, ok := sym.(func(*Commands))
+	if !ok {
+		return fmt.Errorf("插件 '%s' 的 RegisterCommands 的类型 %T 无效, 应为 func(*command.Commands)", path, sym)
+	}
+	if err := c.registerFrom(register); err != nil {
+		return fmt.Errorf("插件 '%s': %s", path, err)
+	}
+	return nil
+}
+
+// Calls register, turning a panic of a failing registration into an
+// error and removing the sub-commands registered before it.
+func (c *Commands) registerFrom(register func(*Commands)) (err error) {
+	n := len(c.list)
+	defer func() {
+		if r := recover(); r != nil {
+			c.list = c.list[:n]
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	register(c)
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Tests if the sub-commands of a plugin built from testdata/plugin are
+// loaded and run. The plugin is loaded by a program built from
+// testdata/pluginhost, as the test binary doesn't link the same build
+// of the package as the plugin.
+func TestLoadPlugin(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd":
+	default:
+		t.Skipf("plugins are not supported on %s", runtime.GOOS)
+	}
+	if testing.Short() {
+		t.Skip("builds a plugin")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is not found")
+	}
+	if out, err := exec.Command(goTool, "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("plugins need cgo")
+	}
+
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "hello.so")
+	host := filepath.Join(dir, "host")
+	for _, args := range [][]string{
+		{"build", "-buildmode=plugin", "-o", plugin, "./testdata/plugin"},
+		{"build", "-o", host, "./testdata/pluginhost"},
+	} {
+		if out, err := exec.Command(goTool, args...).CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	out, err := exec.Command(host, plugin, "hello", "-name", "plugin", "again").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+	if string(out) != "hello plugin again\n" {
+		t.Errorf("expected the hello command of the plugin to run, found %q", out)
+	}
+}
+
+// Tests if loading a file which isn't a plugin fails.
+func TestLoadPluginInvalid(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	path := filepath.Join(t.TempDir(), "hello.so")
+	if err := os.WriteFile(path, []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, filepath.Join(t.TempDir(), "missing.so")} {
+		if err := c.LoadPlugin(p); err == nil || !strings.Contains(err.Error(), p) {
+			t.Errorf("%s: expected an error naming the plugin, found %v", p, err)
+		}
+	}
+}
+
+// Tests if a plugin registering an existing command fails without
+// keeping any of its commands.
+func TestRegisterFromDuplicate(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+
+	err := c.registerFrom(func(c *Commands) {
+		c.On("command2", "", &testCmd2{}, []string{})
+		c.On("command1", "", &testCmd1{}, []string{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "command1") {
+		t.Errorf("expected the duplicate command1 to fail, found %v", err)
+	}
+	if len(c.list) != 1 || c.lookup("command2") != nil {
+		t.Errorf("the commands of the plugin are expected to be removed, found %v", len(c.list))
+	}
+
+	if err := c.registerFrom(func(c *Commands) {
+		c.On("command2", "", &testCmd2{}, []string{})
+	}); err != nil || c.lookup("command2") == nil {
+		t.Errorf("expected command2 to be registered, found %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import "flag"
+
+// The exit code used when a sub-command requiring elevated privileges
+// is run without them, EX_NOPERM of sysexits.h.
+const ExitNoPermission = 77
+
+// Reports whether the process runs with elevated privileges, it can be
+// replaced in tests.
+var isPrivileged = privileged
+
+// Requires the named sub-command to run as root, or as an administrator
+// on windows. Otherwise it is refused with ExitNoPermission, unless the
+// -allow-unprivileged flag added to the sub-command is set.
+func (c *Commands) RequireRoot(name string) {
+	c.mustLookup(name).requireRoot = true
+}
+
+// Defines the -allow-unprivileged flag in fs if subcmd requires root.
+func (c *Commands) privilegeFlags(subcmd *cmdInstance, fs *flag.FlagSet) {
+	if subcmd.requireRoot {
+		fs.BoolVar(&c.flagAllowUnprivileged, "allow-unprivileged", false, "允许在没有管理员权限时运行")
+	}
+}
+
+// Reports an error and returns false if subcmd requires root, but the
+// process isn't privileged.
+func (c *Commands) checkPrivilege(subcmd *cmdInstance) bool {
+	if !subcmd.requireRoot || c.flagAllowUnprivileged || isPrivileged() {
+		return true
+	}
+	ErrOutput("命令 '%s' 需要以管理员权限运行", subcmd.name)
+	return false
+}
+
+// Sets the authorizer deciding which sub-commands are available, e.g.
+// depending on the license or the role of the user. Usage hides the
+// sub-commands it refuses, and Parse rejects them with
+// ExitNoPermission.
+func (c *Commands) SetAuthorizer(authorizer func(cmdName string) bool) {
+	c.authorizer = authorizer
+}
+
+// Reports whether the authorizer allows subcmd and the groups it
+// belongs to, which are named by their paths, e.g. "remote add".
+func (c *Commands) authorized(subcmd *cmdInstance) bool {
+	if c.authorizer == nil {
+		return true
+	}
+	for ; subcmd != nil; subcmd = subcmd.parent {
+		if !c.authorizer(subcmd.pathName()) {
+			return false
+		}
+	}
+	return true
+}
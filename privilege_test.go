@@ -0,0 +1,95 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+// Tests if a command requiring root is refused without privileges,
+// unless explicitly allowed.
+func TestRequireRoot(t *testing.T) {
+	for _, test := range []struct {
+		privileged bool
+		args       []string
+		run        bool
+	}{
+		{true, []string{"command1"}, true},
+		{false, []string{"command1"}, false},
+		{false, []string{"command1", "-allow-unprivileged"}, true},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		old := isPrivileged
+		isPrivileged = func() bool { return test.privileged }
+
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c1 := &testCmd1{}
+		c.On("command1", "", c1, []string{})
+		c.RequireRoot("command1")
+		c.ParseAndRun(test.args)
+		isPrivileged = old
+
+		if c1.run != test.run {
+			t.Errorf("privileged=%v %v: expected run %v, found %v", test.privileged, test.args, test.run, c1.run)
+		}
+		if !test.run && *code != ExitNoPermission {
+			t.Errorf("privileged=%v %v: expected exit code %v, found %v", test.privileged, test.args, ExitNoPermission, *code)
+		}
+	}
+}
+
+// Tests if unauthorized commands are hidden and refused.
+func TestAuthorizer(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	licensed := false
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{})
+	c.On("premium", "a licensed command", &testCmd2{}, []string{})
+	c.OnForward("p", "", "premium", nil)
+	c.SetAuthorizer(func(cmdName string) bool {
+		return cmdName != "premium" || licensed
+	})
+
+	c.Usage()
+	if strings.Contains(stderr.String(), "premium") {
+		t.Errorf("the unauthorized command is expected to be hidden, found %q", stderr.String())
+	}
+	for _, args := range [][]string{{"premium"}, {"p"}} {
+		*code = -100
+		if err := c.parse(args); err == nil || err.code != ExitNoPermission || err.problems[0].Kind != KindNotAvailable {
+			t.Errorf("%v: expected the command to be not available, found %v", args, err)
+		}
+	}
+
+	licensed = true
+	stderr.Reset()
+	c.Usage()
+	if !strings.Contains(stderr.String(), "premium") {
+		t.Errorf("the authorized command is expected to be listed, found %q", stderr.String())
+	}
+
+	c.Parse([]string{"command1"})
+	c.SetAuthorizer(func(cmdName string) bool { return false })
+	c.Run()
+	if c1.run || *code != ExitNoPermission {
+		t.Errorf("the command is expected to be refused by Run, found run %v and exit code %v", c1.run, *code)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+)
+
+// Progress reports the progress of a long running sub-command to
+// StdErr. On a terminal the status line is updated in place, otherwise
+// a line is printed each time another tenth of the total is done.
+type Progress struct {
+	w        io.Writer
+	terminal bool
+	total    int
+
+	// whether the status line is shown on the terminal, and the
+	// tenth of the total last printed otherwise
+	shown bool
+	step  int
+}
+
+// Returns a Progress writing to StdErr for total items of work, a total
+// of 0 or less if it isn't known, then every update is printed.
+func (c *Commands) NewProgress(total int) *Progress {
+	return &Progress{w: StdErr, terminal: isTerminal(StdErr), total: total, step: -1}
+}
+
+// Reports that n items are done, with a message about the current one.
+func (p *Progress) Update(n int, msg string) {
+	line := fmt.Sprintf("[%d] %s", n, msg)
+	if p.total > 0 {
+		line = fmt.Sprintf("[%d/%d] %s", n, p.total, msg)
+	}
+
+	if p.terminal {
+		// rewrites the line, clearing what is left of the previous one
+		fmt.Fprintf(p.w, "\r%s\033[K", line)
+		p.shown = true
+		return
+	}
+
+	if p.total > 0 {
+		step := n * 10 / p.total
+		if step == p.step {
+			return
+		}
+		p.step = step
+	}
+	fmt.Fprintln(p.w, line)
+}
+
+// Reports that all of the work is done, ending the status line.
+func (p *Progress) Done() {
+	if p.terminal {
+		if p.shown {
+			fmt.Fprintln(p.w)
+		}
+		return
+	}
+	if p.total > 0 && p.step < 10 {
+		fmt.Fprintf(p.w, "[%d/%d]\n", p.total, p.total)
+	}
+}
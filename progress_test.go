@@ -0,0 +1,63 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"testing"
+)
+
+// Tests if the progress is updated in place on a terminal.
+func TestProgressTerminal(t *testing.T) {
+	stderr := captureStdErr(t)
+	old := isTerminal
+	isTerminal = func(v interface{}) bool { return true }
+	defer func() { isTerminal = old }()
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	p := c.NewProgress(2)
+	p.Update(1, "copying a.txt")
+	p.Update(2, "b")
+	p.Done()
+	expected := "\r[1/2] copying a.txt\033[K\r[2/2] b\033[K\n"
+	if stderr.String() != expected {
+		t.Errorf("expected %q, found %q", expected, stderr.String())
+	}
+}
+
+// Tests if the progress is printed as lines when redirected.
+func TestProgressLines(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	p := c.NewProgress(20)
+	for i := 1; i <= 5; i++ {
+		p.Update(i, "item")
+	}
+	p.Done()
+	expected := "[1/20] item\n[2/20] item\n[4/20] item\n[20/20]\n"
+	if stderr.String() != expected {
+		t.Errorf("expected %q, found %q", expected, stderr.String())
+	}
+
+	stderr.Reset()
+	p = c.NewProgress(0)
+	p.Update(1, "a")
+	p.Update(2, "b")
+	p.Done()
+	if stderr.String() != "[1] a\n[2] b\n" {
+		t.Errorf("expected every update without a total, found %q", stderr.String())
+	}
+}
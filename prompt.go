@@ -0,0 +1,148 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Reports whether v is a terminal, it can be replaced in tests.
+var isTerminal = func(v interface{}) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Prompts for the missing required flags instead of failing, if the
+// input is a terminal.
+func (c *Commands) SetPromptRequired(b bool) {
+	c.promptRequired = b
+}
+
+// Lets the user pick a sub-command from a numbered list, and prompts
+// for its missing required flags, if no sub-command is given on a
+// terminal. Otherwise the usage is printed as usual.
+func (c *Commands) SetInteractiveFallback(b bool) {
+	c.interactiveFallback = b
+}
+
+// Prompts for the sub-command to run from a numbered list of those the
+// authorizer allows, returning an empty name if the input ends.
+func (c *Commands) chooseCommand() string {
+	var list []*cmdInstance
+	for _, subcmd := range c.list {
+		if c.authorized(subcmd) {
+			list = append(list, subcmd)
+		}
+	}
+	ErrOutput("子命令列表:")
+	for i, subcmd := range list {
+		ErrOutput("  %2d) %-15s %s", i+1, subcmd.name, subcmd.description)
+	}
+	for {
+		line, err := c.prompt("请选择子命令 [1-%d]: ", len(list))
+		if err != nil {
+			return ""
+		}
+		line = strings.TrimSpace(line)
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(list) {
+			return list[n-1].name
+		}
+		if subcmd := c.lookup(line); subcmd != nil && c.authorized(subcmd) {
+			return line
+		}
+	}
+}
+
+// Marks the named sub-command as destructive, it asks for confirmation
+// with the message, e.g. "This will delete all data.", before running
+// unless the -yes or -y flag added to the sub-command is set. Without
+// a terminal to ask on, it refuses to run without the flag.
+func (c *Commands) MarkDestructive(name, message string) {
+	c.mustLookup(name).destructive = message
+}
+
+// Defines the -yes and -y flags in fs if subcmd is destructive.
+func (c *Commands) confirmFlags(subcmd *cmdInstance, fs *flag.FlagSet) {
+	if subcmd.destructive != "" {
+		fs.BoolVar(&c.flagYes, "yes", false, "不需要确认直接运行")
+		fs.BoolVar(&c.flagYes, "y", false, "同 -yes")
+	}
+}
+
+// Asks for confirmation if subcmd is destructive, reporting whether it
+// may run.
+func (c *Commands) confirm(subcmd *cmdInstance) bool {
+	if subcmd.destructive == "" || c.flagYes {
+		return true
+	}
+	if !isTerminal(c.inputSource()) {
+		ErrOutput("命令 '%s' 需要确认, 请使用 -yes 选项", subcmd.name)
+		return false
+	}
+	answer, err := c.prompt("%s 是否继续? [y/N] ", subcmd.destructive)
+	if err == nil {
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		}
+	}
+	ErrOutput("已取消")
+	return false
+}
+
+// Prints the prompt to StdErr and reads a line from the input, without
+// the line ending.
+func (c *Commands) prompt(msg string, args ...interface{}) (string, error) {
+	fmt.Fprintf(StdErr, msg, args...)
+	line, err := c.Stdin().ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Prompts for the value of each missing flag and sets it, returning
+// the flags which are still missing.
+func (c *Commands) promptFlags(fs *flag.FlagSet, missing []string) []string {
+	if !(c.promptRequired || c.guided) || c.checkMode || !isTerminal(c.inputSource()) {
+		return missing
+	}
+	for _, name := range missing {
+		for {
+			value, err := c.prompt("请输入 -%s 的值: ", name)
+			if err != nil {
+				break
+			}
+			if err := fs.Set(name, value); err != nil {
+				ErrOutput("%s", err)
+				continue
+			}
+			break
+		}
+	}
+	return missingFlags(fs, missing)
+}
@@ -0,0 +1,200 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Feeds input to StdInput as if it was a terminal until the test
+// finishes.
+func fakeTerminal(t *testing.T, input string) {
+	oldInput, oldIsTerminal := StdInput, isTerminal
+	StdInput = strings.NewReader(input)
+	isTerminal = func(v interface{}) bool {
+		_, ok := v.(io.Reader)
+		return ok
+	}
+	t.Cleanup(func() {
+		StdInput, isTerminal = oldInput, oldIsTerminal
+	})
+}
+
+// testStringCmd is a test sub command with a string flag.
+type testStringCmd struct {
+	token *string
+
+	run bool
+}
+
+func (cmd *testStringCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	cmd.token = fs.String("token", "", "Description about token")
+	return fs
+}
+
+func (cmd *testStringCmd) Run(args []string) error {
+	cmd.run = true
+	return nil
+}
+
+// Tests if a missing required flag is prompted for on a terminal.
+func TestPromptRequired(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+	fakeTerminal(t, "secret\n")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStringCmd{}
+	c.On("login", "", cmd, []string{"token"})
+	c.SetPromptRequired(true)
+	c.ParseAndRun([]string{"login"})
+	if *code != -100 {
+		t.Errorf("no exit is expected, found exit code %v", *code)
+	}
+	if !cmd.run || *cmd.token != "secret" {
+		t.Errorf("expected to run with token 'secret', found %v '%s'", cmd.run, *cmd.token)
+	}
+}
+
+// Tests if a missing required flag fails if the input isn't a terminal.
+func TestPromptRequiredNotTerminal(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+	oldInput := StdInput
+	StdInput = strings.NewReader("secret\n")
+	defer func() { StdInput = oldInput }()
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStringCmd{}
+	c.On("login", "", cmd, []string{"token"})
+	c.SetPromptRequired(true)
+	c.Parse([]string{"login"})
+	if *code != 1 {
+		t.Errorf("expected exit code 1, found %v", *code)
+	}
+}
+
+// Tests if the command and its required flags are prompted for if no
+// command is given on a terminal.
+func TestInteractiveFallback(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+	fakeTerminal(t, "3\nlogin\nsecret\n")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	login := &testStringCmd{}
+	c.On("command1", "", c1, []string{})
+	c.On("login", "description of login", login, []string{"token"})
+	c.SetInteractiveFallback(true)
+	c.ParseAndRun(nil)
+	if *code != -100 {
+		t.Errorf("no exit is expected, found exit code %v", *code)
+	}
+	if c1.run || !login.run || *login.token != "secret" {
+		t.Errorf("expected login to run with token 'secret', found %v '%s'", login.run, *login.token)
+	}
+	if !strings.Contains(stderr.String(), " 2) login") {
+		t.Errorf("expected the numbered list of commands, found %q", stderr.String())
+	}
+}
+
+// Tests if the commands refused by the authorizer are neither listed
+// nor accepted by name.
+func TestInteractiveFallbackRefused(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+	fakeTerminal(t, "secret\n2\n1\n")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	secret := &testCmd1{}
+	c.On("secret", "description of secret", secret, []string{})
+	c.On("command1", "", c1, []string{})
+	c.SetAuthorizer(func(name string) bool { return name != "secret" })
+	c.SetInteractiveFallback(true)
+	c.ParseAndRun(nil)
+	if *code != -100 {
+		t.Errorf("no exit is expected, found exit code %v", *code)
+	}
+	if secret.run || !c1.run {
+		t.Errorf("expected command1 to run, found %v %v", secret.run, c1.run)
+	}
+	if strings.Contains(stderr.String(), "secret") {
+		t.Errorf("expected secret not to be listed, found %q", stderr.String())
+	}
+}
+
+// Tests if the usage is printed if no command is given without a
+// terminal.
+func TestInteractiveFallbackNotTerminal(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+	fakeStdInput(t, "1\n")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{})
+	c.SetInteractiveFallback(true)
+	c.Parse(nil)
+	if *code != 1 || c.matchingCmd != nil {
+		t.Errorf("expected exit code 1 without a matching command, found %v", *code)
+	}
+}
+
+// Tests if a destructive command runs only when confirmed.
+func TestDestructive(t *testing.T) {
+	for _, test := range []struct {
+		terminal bool
+		input    string
+		args     []string
+		run      bool
+	}{
+		{true, "y\n", []string{"drop"}, true},
+		{true, "n\n", []string{"drop"}, false},
+		{true, "", []string{"drop"}, false},
+		{false, "y\n", []string{"drop"}, false},
+		{false, "", []string{"drop", "-yes"}, true},
+		{false, "", []string{"drop", "-y"}, true},
+	} {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			stderr := captureStdErr(t)
+			code := captureExit(t)
+			if test.terminal {
+				fakeTerminal(t, test.input)
+			} else {
+				fakeStdInput(t, test.input)
+			}
+
+			c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+			c1 := &testCmd1{}
+			c.On("drop", "", c1, []string{})
+			c.MarkDestructive("drop", "将删除所有数据。")
+			c.ParseAndRun(test.args)
+			if c1.run != test.run {
+				t.Errorf("terminal=%v %q: expected run %v, found %v", test.terminal, test.input, test.run, c1.run)
+			}
+			if !test.run && *code != 1 {
+				t.Errorf("terminal=%v %q: expected exit code 1, found %v", test.terminal, test.input, *code)
+			}
+			if test.terminal && !strings.Contains(stderr.String(), "将删除所有数据。 是否继续? [y/N]") {
+				t.Errorf("terminal=%v %q: expected the confirmation, found %q", test.terminal, test.input, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CmdInfo describes a registered sub-command for a HelpRenderer.
+type CmdInfo struct {
+	Name        string
+	Aliases     []string
+	Description string
+
+	// The names leading to the sub-command, and how it is invoked
+	// as shown in the usage, e.g. "prog name".
+	Path       []string
+	Invocation string
+
+	// The sub-command and the arguments it forwards to, if any.
+	Forward     string
+	ForwardArgs []string
+
+	Categories []string
+
+	// The placeholder of the arguments passed through after "--".
+	Passthrough string
+
+	// The placeholder of the arguments joined into one, see
+	// SetJoinArgs.
+	JoinArgs string
+
+	// The flags of the sub-command, or of the target of a forwarding
+	// sub-command; nil if the target doesn't exist. Only set for the
+	// usage of the sub-command, not by List, as defining the flags
+	// resets the values the sub-commands bind them to.
+	Flags         *flag.FlagSet
+	RequiredFlags []string
+	Constraints   []Constraint
+
+	Examples []string
+
+	// See Annotate.
+	Annotations map[string]string
+
+	// The sub-commands of a group, except those refused by the
+	// authorizer, see OnGroup; nil if it isn't a group.
+	Commands []CmdInfo
+}
+
+// HelpRenderer renders the usage, to replace the built-in layout, e.g.
+// with JSON or HTML.
+type HelpRenderer interface {
+	// Renders the top-level usage, listing the sub-commands.
+	RenderUsage(c *Commands, w io.Writer)
+
+	// Renders the usage of a sub-command.
+	RenderSubcommandUsage(c *Commands, cmd CmdInfo, w io.Writer)
+}
+
+// Sets the renderer of the usage, nil restores the built-in one. A
+// sub-command with its own Usage method still prints its usage itself.
+func (c *Commands) SetHelpRenderer(r HelpRenderer) {
+	c.helpRenderer = r
+}
+
+func (c *Commands) renderer() HelpRenderer {
+	if c.helpRenderer != nil {
+		return c.helpRenderer
+	}
+	return defaultRenderer{}
+}
+
+// Returns the registered sub-commands in the order of registration,
+// except those refused by the authorizer, without their Flags.
+func (c *Commands) List() []CmdInfo {
+	var infos []CmdInfo
+	for _, subcmd := range c.list {
+		if c.authorized(subcmd) {
+			infos = append(infos, c.cmdInfo(subcmd, false))
+		}
+	}
+	return infos
+}
+
+// Returns the description of subcmd, with its flags if withFlags.
+func (c *Commands) cmdInfo(subcmd *cmdInstance, withFlags bool) CmdInfo {
+	info := CmdInfo{
+		Name:          subcmd.name,
+		Aliases:       subcmd.aliases,
+		Description:   subcmd.description,
+		Path:          subcmd.path(),
+		Invocation:    c.usagePath(subcmd),
+		Forward:       subcmd.forward,
+		ForwardArgs:   subcmd.forwardArgs,
+		Categories:    subcmd.categories,
+		Passthrough:   subcmd.passthrough,
+		RequiredFlags: subcmd.requiredFlags,
+		Examples:      subcmd.examples,
+		Annotations:   subcmd.annotations,
+	}
+	if subcmd.group != nil {
+		info.Commands = []CmdInfo{}
+		for _, child := range subcmd.group.list {
+			if c.authorized(child) {
+				info.Commands = append(info.Commands, c.cmdInfo(child, false))
+			}
+		}
+	}
+	if target, _ := c.resolve(subcmd, nil); target != nil {
+		if withFlags {
+			info.Flags = target.command.Flags(flag.NewFlagSet(subcmd.name, flag.ContinueOnError))
+			c.redactDefaults(subcmd, info.Flags)
+			c.helpFlags(info.Flags, new(bool))
+		}
+		info.RequiredFlags = target.requiredFlags
+		info.Constraints = target.constraints
+		info.JoinArgs = target.joinArgs
+	}
+	return info
+}
+
+// Returns the name of a sub-command followed by its aliases, as listed
+// in the usage, e.g. "checkout, co".
+func listName(name string, aliases []string) string {
+	return strings.Join(append([]string{name}, aliases...), ", ")
+}
+
+// defaultRenderer is the built-in HelpRenderer.
+type defaultRenderer struct{}
+
+func fprintln(w io.Writer, msg string, args ...interface{}) {
+	fmt.Fprintf(w, msg, args...)
+	fmt.Fprintln(w)
+}
+
+func (defaultRenderer) RenderUsage(c *Commands, w io.Writer) {
+	if len(c.list) == 0 {
+		// no subcommands
+		if c.topLevelUsage != nil {
+			c.topLevelUsage(w)
+			return
+		}
+		fprintln(w, "使用方法: %s [选项]", c.program)
+		c.printGlobalDefaults(w)
+		return
+	}
+
+	fprintln(w, "使用方法: %s [选项] 子命令 [选项] \n", c.program)
+	fprintln(w, "子命令列表:")
+	var categories []string
+	byCategory := make(map[string][]CmdInfo)
+	for _, info := range c.List() {
+		if len(info.Categories) == 0 {
+			fprintln(w, "  %-15s %s", listName(info.Name, info.Aliases), info.Description)
+			continue
+		}
+		for _, category := range info.Categories {
+			if _, ok := byCategory[category]; !ok {
+				categories = append(categories, category)
+			}
+			byCategory[category] = append(byCategory[category], info)
+		}
+	}
+	for _, category := range categories {
+		fprintln(w, "\n%s:", category)
+		for _, info := range byCategory[category] {
+			fprintln(w, "  %-15s %s", listName(info.Name, info.Aliases), info.Description)
+		}
+	}
+
+	if names := flagNames(c.flags); names != "" && c.usageVerbosity >= UsageFlags {
+		fprintln(w, "\n选项:")
+		if c.helpLevel == HelpSummary {
+			fprintln(w, "  %s", names)
+		} else {
+			c.printGlobalDefaults(w)
+		}
+	}
+	fprintln(w, "\n查看子命令的帮助: %s 子命令 -h", c.program)
+}
+
+func (defaultRenderer) RenderSubcommandUsage(c *Commands, cmd CmdInfo, w io.Writer) {
+	if cmd.Commands != nil {
+		renderGroupUsage(cmd, w)
+		return
+	}
+	passthrough := ""
+	if cmd.JoinArgs != "" {
+		passthrough = " <" + cmd.JoinArgs + "...>"
+	}
+	if cmd.Passthrough != "" {
+		passthrough += " -- " + cmd.Passthrough
+	}
+	if c.helpLevel == HelpSummary {
+		// only the synopsis and the names of the flags
+		if cmd.Flags == nil || flagNames(cmd.Flags) == "" {
+			fprintln(w, "使用方法: %s%s", cmd.Invocation, passthrough)
+			return
+		}
+		fprintln(w, "使用方法: %s [选项]%s", cmd.Invocation, passthrough)
+		fprintln(w, "选项: %s", flagNames(cmd.Flags))
+		return
+	}
+
+	fprintln(w, "%s", cmd.Description)
+	if len(cmd.Aliases) > 0 {
+		fprintln(w, "别名: %s", strings.Join(cmd.Aliases, ", "))
+	}
+	if cmd.Forward != "" {
+		fprintln(w, "等同于: %s %s", c.program, strings.Join(append([]string{cmd.Forward}, cmd.ForwardArgs...), " "))
+	}
+	if cmd.Flags == nil {
+		return
+	}
+	cmd.Flags.SetOutput(w)
+	if flagNames(cmd.Flags) != "" {
+		fprintln(w, "使用方法: %s [选项]%s", cmd.Invocation, passthrough)
+		if c.usageVerbosity >= UsageFlags {
+			cmd.Flags.PrintDefaults()
+		}
+		if len(cmd.Constraints) > 0 && c.usageVerbosity >= UsageFull {
+			fprintln(w, "\n约束:")
+			for _, ct := range cmd.Constraints {
+				fprintln(w, "  %s", ct)
+			}
+		}
+	} else if passthrough != "" {
+		fprintln(w, "使用方法: %s%s", cmd.Invocation, passthrough)
+	}
+	if len(cmd.Examples) > 0 && c.usageVerbosity >= UsageFull {
+		fprintln(w, "\n示例:")
+		for _, example := range cmd.Examples {
+			fprintln(w, "  %s", example)
+		}
+	}
+}
+
+// Lists the sub-commands of the group cmd.
+func renderGroupUsage(cmd CmdInfo, w io.Writer) {
+	if cmd.Description != "" {
+		fprintln(w, "%s\n", cmd.Description)
+	}
+	fprintln(w, "使用方法: %s 子命令 [选项]\n", cmd.Invocation)
+	fprintln(w, "子命令列表:")
+	for _, info := range cmd.Commands {
+		fprintln(w, "  %-15s %s", listName(info.Name, info.Aliases), info.Description)
+	}
+	fprintln(w, "\n查看子命令的帮助: %s 子命令 -h", cmd.Invocation)
+}
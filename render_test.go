@@ -0,0 +1,95 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testRenderer renders the usage as one line per item.
+type testRenderer struct{}
+
+func (testRenderer) RenderUsage(c *Commands, w io.Writer) {
+	for _, info := range c.List() {
+		fmt.Fprintf(w, "command %s\n", info.Name)
+	}
+}
+
+func (testRenderer) RenderSubcommandUsage(c *Commands, cmd CmdInfo, w io.Writer) {
+	fmt.Fprintf(w, "usage %s\n", cmd.Invocation)
+	cmd.Flags.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "flag %s\n", f.Name)
+	})
+	for _, ct := range cmd.Constraints {
+		fmt.Fprintf(w, "constraint %s\n", ct)
+	}
+}
+
+// Tests if a custom renderer replaces the built-in usage.
+func TestHelpRenderer(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	c.MarkFlagsMutuallyExclusive("all", "int", "int64")
+	c.OnForward("i", "", "all", []string{"-int=1"})
+	c.SetHelpRenderer(testRenderer{})
+
+	c.Usage()
+	if stderr.String() != "command all\ncommand i\n" {
+		t.Errorf("expected the custom usage, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	c.SubcommandUsage(c.lookup("i"))
+	for _, s := range []string{"usage cmd i\n", "flag int64\n", "constraint -int, -int64 不能同时指定\n"} {
+		if !strings.Contains(stderr.String(), s) {
+			t.Errorf("expected %q in the custom usage, found %q", s, stderr.String())
+		}
+	}
+
+	stderr.Reset()
+	c.SetHelpRenderer(nil)
+	c.Usage()
+	if !strings.Contains(stderr.String(), "子命令列表:") {
+		t.Errorf("expected the built-in usage, found %q", stderr.String())
+	}
+}
+
+// Tests if listing the sub-commands, e.g. for the usage, leaves the
+// values bound to their flags alone.
+func TestListKeepsParsedFlags(t *testing.T) {
+	captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStringCmd{}
+	c.On("login", "", cmd, []string{})
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	c.Parse([]string{"login", "-token", "abc"})
+
+	for _, info := range c.List() {
+		if info.Flags != nil {
+			t.Errorf("the flags of %s are not expected to be listed", info.Name)
+		}
+	}
+	c.Usage()
+	if *cmd.token != "abc" {
+		t.Errorf("expected the parsed token, found %q", *cmd.token)
+	}
+}
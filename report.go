@@ -0,0 +1,203 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// The kinds of problems Parse reports, as they appear in the json
+// error format.
+const (
+	KindNoCommand           = "no_command"
+	KindUnknownCommand      = "unknown_command"
+	KindInvalidFlag         = "invalid_flag"
+	KindMissingRequiredFlag = "missing_required_flag"
+	KindFlagConstraint      = "flag_constraint"
+	KindStdin               = "stdin"
+	KindNotAvailable        = "not_available"
+	KindArgsEnv             = "args_env"
+	KindInvalidArg          = "invalid_arg"
+	KindAliasLoop           = "alias_loop"
+)
+
+// problem is a single reason Parse fails.
+type problem struct {
+	Kind    string   `json:"kind"`
+	Command string   `json:"command,omitempty"`
+	Flags   []string `json:"flags,omitempty"`
+	Message string   `json:"message"`
+}
+
+// parseError is the failure of parse, which Parse reports before
+// exiting with code.
+type parseError struct {
+	problems []problem
+
+	// Prints the usage of subcmd after the problems, or the top-level
+	// usage if subcmd is nil.
+	usage  bool
+	subcmd *cmdInstance
+
+	// Prints the hint pointing to the usage after the problems.
+	hint bool
+
+	code int
+}
+
+func (e *parseError) Error() string {
+	messages := make([]string, len(e.problems))
+	for i, p := range e.problems {
+		messages[i] = p.Message
+	}
+	return strings.Join(messages, "\n")
+}
+
+// The failures of parsing when no sub-command is given, and when the
+// one given isn't registered, as matched by errors.Is.
+var (
+	ErrNoSubcommand      = errors.New("需要指定子命令")
+	ErrUnknownSubcommand = errors.New("未知的子命令")
+)
+
+// CommandError is the failure of parsing with a single problem.
+type CommandError struct {
+	// The sub-command given, if any.
+	Command string
+
+	Message string
+
+	// ErrNoSubcommand, ErrUnknownSubcommand or nil.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is the failure of parsing when several validations
+// fail at once, e.g. a required flag is missing and two mutually
+// exclusive flags are set, so that all of them are reported together.
+type ValidationError struct {
+	// The sub-command being parsed.
+	Command string
+
+	Problems []error
+}
+
+// Lists the problems, one per line.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, err := range e.Problems {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// Returns the error to report e as to callers, a *ValidationError if
+// it has several problems, a *CommandError otherwise.
+func (e *parseError) asError() error {
+	if len(e.problems) == 1 {
+		p := e.problems[0]
+		err := &CommandError{Command: p.Command, Message: p.Message}
+		switch {
+		case p.Kind == KindNoCommand:
+			err.Err = ErrNoSubcommand
+		case p.Kind == KindUnknownCommand && (e.subcmd == nil || e.subcmd.group != nil):
+			// not the unknown target of a forwarding sub-command
+			err.Err = ErrUnknownSubcommand
+		}
+		return err
+	}
+	if len(e.problems) == 0 {
+		return e
+	}
+	v := &ValidationError{Command: e.problems[0].Command}
+	for _, p := range e.problems {
+		v.Problems = append(v.Problems, errors.New(p.Message))
+	}
+	return v
+}
+
+// Returns a parseError with a single problem of subcmd.
+func (subcmd *cmdInstance) failure(kind string, flags []string, message string, code int, usage bool) *parseError {
+	return &parseError{
+		problems: []problem{{Kind: kind, Command: subcmd.name, Flags: flags, Message: message}},
+		usage:    usage,
+		subcmd:   subcmd,
+		code:     code,
+	}
+}
+
+// Sets the format the failures of Parse are reported in to StdErr,
+// either text (the default) which prints the problems followed by the
+// usage, or json which prints a JSON object for each problem, with
+// its kind, the command, the flags involved and the message.
+func (c *Commands) SetErrorFormat(format string) {
+	c.errorFormat = format
+}
+
+// Defines a global flag named name selecting the error format, see
+// SetErrorFormat.
+func (c *Commands) EnableErrorFormatFlag(name string) {
+	if c.errorFormat == "" {
+		c.errorFormat = "text"
+	}
+	c.flags.StringVar(&c.errorFormat, name, c.errorFormat, "错误的输出格式: text 或 json")
+}
+
+// Reports the failure of Parse in the error format.
+func (c *Commands) reportParseError(e *parseError) {
+	if c.errorFormat == "json" {
+		for _, p := range e.problems {
+			data, _ := json.Marshal(p)
+			ErrOutput("%s", data)
+		}
+		return
+	}
+
+	usage := e.usage && !c.suppressUsage
+	for _, p := range e.problems {
+		// the usage says it all
+		if p.Kind != KindNoCommand || !usage {
+			ErrOutput("%s", p.Message)
+		}
+	}
+	// the hint is only needed without the usage
+	if e.hint && !c.noUsageHint && !usage {
+		invocation := c.program
+		if e.subcmd != nil {
+			invocation += " " + e.subcmd.pathName()
+		}
+		ErrOutput("运行 '%s -h' 查看使用方法。", invocation)
+	}
+	if usage {
+		if e.subcmd != nil {
+			c.SubcommandUsage(e.subcmd)
+		} else {
+			c.Usage()
+		}
+	}
+}
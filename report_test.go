@@ -0,0 +1,246 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests if the failures of Parse are reported as JSON in the json
+// error format.
+func TestErrorFormatJSON(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		expected problem
+		code     int
+	}{
+		{[]string{"login"}, problem{
+			Kind:    KindMissingRequiredFlag,
+			Command: "login",
+			Flags:   []string{"token"},
+			Message: "缺少必需的选项: -token",
+		}, 1},
+		{[]string{"login", "-unknown"}, problem{
+			Kind:    KindInvalidFlag,
+			Command: "login",
+			Message: "flag provided but not defined: -unknown",
+		}, 2},
+		{[]string{"logout"}, problem{
+			Kind:    KindUnknownCommand,
+			Command: "logout",
+			Message: `未知的子命令: "logout"`,
+		}, 1},
+	} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("login", "", &testStringCmd{}, []string{"token"})
+		c.EnableErrorFormatFlag("error-format")
+		if err := c.flags.Parse([]string{"-error-format=json"}); err != nil {
+			t.Fatal(err)
+		}
+		c.Parse(test.args)
+
+		var found problem
+		if err := json.Unmarshal(stderr.Bytes(), &found); err != nil {
+			t.Errorf("%v: expected a JSON object, found %q", test.args, stderr.String())
+			continue
+		}
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("%v: expected %+v, found %+v", test.args, test.expected, found)
+		}
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+	}
+}
+
+// Tests if the failures of Parse are reported as text by default.
+func TestErrorFormatText(t *testing.T) {
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("login", "description of login", &testStringCmd{}, []string{"token"})
+	c.Parse([]string{"login"})
+	if !strings.HasPrefix(stderr.String(), "缺少必需的选项: -token\ndescription of login\n") {
+		t.Errorf("expected the problem followed by the usage, found %q", stderr.String())
+	}
+}
+
+// Tests if only the messages are printed when the usage is suppressed,
+// unless it is asked for.
+func TestSuppressUsageOnError(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("login", "description of login", &testStringCmd{}, []string{"token"})
+	c.On("fail", "", &testErrCmd{err: &Error{Code: 3, Message: "boom", Help: true}}, []string{})
+	c.SetSuppressUsageOnError(true)
+
+	for _, test := range []struct {
+		args     []string
+		expected string
+		code     int
+	}{
+		{[]string{"login"}, "缺少必需的选项: -token\n", 1},
+		{nil, "需要指定子命令\n", 1},
+		{[]string{"fail"}, "FATAL: fail: boom\n", 3},
+	} {
+		stderr.Reset()
+		*code = -100
+		c.ParseAndRun(test.args)
+		if stderr.String() != test.expected || *code != test.code {
+			t.Errorf("%v: expected %q and %d, found %q and %d", test.args, test.expected, test.code, stderr.String(), *code)
+		}
+	}
+
+	stderr.Reset()
+	c.ParseAndRun([]string{"login", "-h"})
+	if !strings.Contains(stderr.String(), "description of login") {
+		t.Errorf("expected the usage asked for, found %q", stderr.String())
+	}
+}
+
+// Tests if ParseErr returns the failures, matching the sentinel errors,
+// without reporting them or exiting.
+func TestParseErr(t *testing.T) {
+	stderr := captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("login", "", &testStringCmd{}, []string{"token"})
+	c.OnForward("broken", "", "missing", nil)
+	for _, test := range []struct {
+		args     []string
+		sentinel error
+		command  string
+	}{
+		{nil, ErrNoSubcommand, ""},
+		{[]string{"logn"}, ErrUnknownSubcommand, "logn"},
+		{[]string{"broken"}, nil, "broken"},
+		{[]string{"login"}, nil, "login"},
+	} {
+		err := c.ParseErr(test.args)
+		var cmdErr *CommandError
+		if !errors.As(err, &cmdErr) {
+			t.Errorf("%v: expected a *CommandError, found %T", test.args, err)
+			continue
+		}
+		if cmdErr.Command != test.command || cmdErr.Err != test.sentinel {
+			t.Errorf("%v: expected %q and %v, found %q and %v", test.args, test.command, test.sentinel, cmdErr.Command, cmdErr.Err)
+		}
+		if test.sentinel != nil && !errors.Is(err, test.sentinel) {
+			t.Errorf("%v: expected to match %v", test.args, test.sentinel)
+		}
+	}
+	if err := c.ParseErr([]string{"login", "-token", "a"}); err != nil {
+		t.Errorf("no failure is expected, found %v", err)
+	}
+	if stderr.String() != "" || *code != -100 {
+		t.Errorf("expected neither output nor exit, found %q and %d", stderr.String(), *code)
+	}
+}
+
+// Tests if nothing runs after a failed parse, whichever way it's
+// parsed.
+func TestRunAfterParseFailure(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testCmd1{}
+	c.On("command1", "", cmd, []string{"flag1"})
+	for name, parse := range map[string]func() error{
+		"ParseErr":    func() error { return c.ParseErr([]string{"command1", "arg"}) },
+		"ParseString": func() error { return c.ParseString("command1 arg", " ") },
+		"ParseWith":   func() error { return c.ParseWith([]string{"command1", "arg"}, ParseOptions{}) },
+	} {
+		if err := c.ParseErr([]string{"command1", "-flag1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := parse(); err == nil {
+			t.Fatalf("%s: the missing flag1 is expected to fail", name)
+		}
+		cmd.run = false
+		c.Run()
+		if cmd.run || c.args != nil {
+			t.Errorf("%s: nothing is expected to run after the failure, found %v", name, c.args)
+		}
+	}
+	if *code != -100 {
+		t.Errorf("no exit is expected, found %d", *code)
+	}
+}
+
+// Tests if no sub-command fails with ErrNoSubcommand and its own exit
+// code, unlike an unknown one.
+func TestNoCommand(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.SetUsageErrorCode(64)
+	c.SetNoCommandCode(3)
+
+	if err := c.ParseErr(nil); !errors.Is(err, ErrNoSubcommand) || errors.Is(err, ErrUnknownSubcommand) {
+		t.Errorf("expected ErrNoSubcommand, found %v", err)
+	}
+	c.Parse(nil)
+	if *code != 3 {
+		t.Errorf("expected exit code 3, found %d", *code)
+	}
+	c.Parse([]string{"unknown"})
+	if *code != 64 {
+		t.Errorf("expected exit code 64 for an unknown command, found %d", *code)
+	}
+}
+
+// Tests if simultaneous validation failures are reported together.
+func TestValidationError(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{"string"})
+	c.MarkFlagsMutuallyExclusive("all", "int", "bool")
+
+	e := c.parse([]string{"all", "-int", "1", "-bool"})
+	if e == nil || len(e.problems) != 2 {
+		t.Fatalf("expected two problems, found %v", e)
+	}
+	if e.problems[0].Kind != KindMissingRequiredFlag || e.problems[1].Kind != KindFlagConstraint {
+		t.Errorf("expected the missing flag and the constraint, found %+v", e.problems)
+	}
+	err, ok := e.asError().(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError, found %T", e.asError())
+	}
+	if err.Command != "all" || len(err.Problems) != 2 {
+		t.Errorf("unexpected validation error %+v", err)
+	}
+	if expected := "缺少必需的选项: -string\n-int, -bool 不能同时指定"; err.Error() != expected {
+		t.Errorf("expected %q, found %q", expected, err.Error())
+	}
+
+	if _, ok := c.parse([]string{"all", "-int", "1"}).asError().(*ValidationError); ok {
+		t.Error("a single problem is not expected to be a *ValidationError")
+	}
+}
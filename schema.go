@@ -0,0 +1,101 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// schemaProperty is the JSON Schema of a flag.
+type schemaProperty struct {
+	Type        string      `json:"type"`
+	Format      string      `json:"format,omitempty"`
+	Minimum     *int        `json:"minimum,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+	WriteOnly   bool        `json:"writeOnly,omitempty"`
+}
+
+// flagSchema is the JSON Schema of the flags of a sub-command.
+type flagSchema struct {
+	Schema               string                    `json:"$schema"`
+	Title                string                    `json:"title"`
+	Description          string                    `json:"description,omitempty"`
+	Type                 string                    `json:"type"`
+	Properties           map[string]schemaProperty `json:"properties"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties bool                      `json:"additionalProperties"`
+}
+
+// Returns a JSON Schema describing the flags of the named sub-command
+// as the properties of an object, with their types, defaults and
+// usages, and the required flags. Durations are strings in the format
+// of time.ParseDuration, and flags of other types are strings.
+func (c *Commands) FlagJSONSchema(cmdName string) ([]byte, error) {
+	metas, err := c.FlagMetadata(cmdName)
+	if err != nil {
+		return nil, err
+	}
+	schema := flagSchema{
+		Schema:      "https://json-schema.org/draft/2020-12/schema",
+		Title:       cmdName,
+		Description: c.find(cmdName).description,
+		Type:        "object",
+		Properties:  make(map[string]schemaProperty),
+	}
+	zero := 0
+	for _, meta := range metas {
+		p := schemaProperty{Type: "string", Description: meta.Usage, WriteOnly: meta.Sensitive}
+		switch meta.Type {
+		case "bool":
+			p.Type = "boolean"
+			if b, err := strconv.ParseBool(meta.Default); err == nil && b {
+				p.Default = b
+			}
+		case "int", "int64", "uint", "uint64":
+			p.Type = "integer"
+			if meta.Type == "uint" || meta.Type == "uint64" {
+				p.Minimum = &zero
+			}
+			if n, err := strconv.ParseInt(meta.Default, 0, 64); err == nil && n != 0 {
+				p.Default = n
+			}
+		case "float64":
+			p.Type = "number"
+			if f, err := strconv.ParseFloat(meta.Default, 64); err == nil && f != 0 {
+				p.Default = f
+			}
+		case "duration":
+			p.Format = "duration"
+			if meta.Default != "0s" {
+				p.Default = meta.Default
+			}
+		default:
+			if meta.Default != "" {
+				p.Default = meta.Default
+			}
+		}
+		if meta.Sensitive {
+			// the value is a secret, not the redacted default
+			p.Default = nil
+		}
+		schema.Properties[meta.Name] = p
+		if meta.Required {
+			schema.Required = append(schema.Required, meta.Name)
+		}
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
@@ -0,0 +1,64 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// Tests if the schema describes the flags and the required ones.
+func TestFlagJSONSchema(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "all kinds of flags", &testAllFlagsCmd{}, []string{"func", "bool"})
+
+	data, err := c.FlagJSONSchema("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Type       string                            `json:"type"`
+		Properties map[string]map[string]interface{} `json:"properties"`
+		Required   []string                          `json:"required"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Type != "object" || len(schema.Properties) != 9 {
+		t.Errorf("expected an object with 9 properties, found %s", data)
+	}
+	for name, expected := range map[string]map[string]interface{}{
+		"bool":     {"type": "boolean", "description": "a bool"},
+		"int":      {"type": "integer", "default": 1.0, "description": "an int"},
+		"uint64":   {"type": "integer", "minimum": 0.0, "default": 4.0, "description": "an uint64"},
+		"float64":  {"type": "number", "default": 0.5, "description": "a float64"},
+		"string":   {"type": "string", "default": "s", "description": "a string"},
+		"duration": {"type": "string", "format": "duration", "default": "1s", "description": "a duration"},
+		"func":     {"type": "string", "description": "a func"},
+	} {
+		if !reflect.DeepEqual(schema.Properties[name], expected) {
+			t.Errorf("%s: expected %v, found %v", name, expected, schema.Properties[name])
+		}
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"bool", "func"}) {
+		t.Errorf("expected bool and func to be required, found %v", schema.Required)
+	}
+
+	if _, err := c.FlagJSONSchema("none"); err == nil {
+		t.Error("an unknown command is expected to fail")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// scriptCmd is a sub command backed by an executable file, all
+// arguments are passed to it untouched.
+type scriptCmd struct {
+	c    *Commands
+	path string
+}
+
+func (cmd *scriptCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+// Runs the script, a non-zero exit status of the script becomes an
+// *Error with the same code and without a message, since the script
+// reports the failure itself. A script killed by a signal results in
+// the code 128+signal, as in shells.
+func (cmd *scriptCmd) Run(args []string) error {
+	c := exec.Command(cmd.path, args...)
+	c.Stdin = cmd.stdin()
+	c.Stdout = StdOutput
+	c.Stderr = StdErr
+	err := c.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &Error{Code: exitStatus(exitErr)}
+	}
+	return err
+}
+
+// Returns the input of the script, see SetInput. A file is passed as is
+// unless a prompt read ahead of it, as the script would otherwise have
+// to wait for the end of a terminal.
+func (cmd *scriptCmd) stdin() io.Reader {
+	r := cmd.c.Stdin()
+	if f, ok := cmd.c.inputSource().(*os.File); ok && r.Buffered() == 0 {
+		return f
+	}
+	return r
+}
+
+// Registers every executable file in dir as a sub-command named after
+// the file without its extension. The first comment line of the script
+// is used as the description, and the arguments of the sub-command are
+// passed to the script as is. Symbolic links are followed, e.g. to
+// scripts installed elsewhere. Nothing is registered if any of the
+// names is taken, by a sub-command or by another script.
+func (c *Commands) LoadScriptDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var scripts []*scriptCmd
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			if entry.Type()&os.ModeSymlink != 0 {
+				// a broken link
+				continue
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if c.lookup(name) != nil || names[name] {
+			return errors.New("命令 '" + name + "' 已存在")
+		}
+		names[name] = true
+		scripts = append(scripts, &scriptCmd{c: c, path: path})
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script.path), filepath.Ext(script.path))
+		c.On(name, scriptDescription(script.path), script, nil)
+		c.list[len(c.list)-1].rawArgs = true
+	}
+	return nil
+}
+
+// Returns the first comment line of the script, skipping the shebang.
+func scriptDescription(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#!") {
+			continue
+		}
+		for _, prefix := range []string{"#", "//", "::", "REM "} {
+			if strings.HasPrefix(line, prefix) {
+				return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+		return ""
+	}
+	return ""
+}
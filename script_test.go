@@ -0,0 +1,170 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Tests if executable scripts in a directory become sub-commands.
+func TestLoadScriptDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\n# says hello\necho hello \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "hello.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("# not a script\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	if err := c.LoadScriptDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.list) != 1 {
+		t.Fatalf("only one script command is expected, found %v", len(c.list))
+	}
+	if c.list[0].name != "hello" || c.list[0].description != "says hello" {
+		t.Errorf("expected hello with description 'says hello', found %s with '%s'",
+			c.list[0].name, c.list[0].description)
+	}
+
+	var stdout bytes.Buffer
+	old := StdOutput
+	StdOutput = &stdout
+	defer func() { StdOutput = old }()
+
+	c.ParseAndRun([]string{"hello", "-v", "world"})
+	if stdout.String() != "hello -v world\n" {
+		t.Errorf("expected 'hello -v world', found %q", stdout.String())
+	}
+
+	if err := c.LoadScriptDir(dir); err == nil {
+		t.Error("loading the same script twice is expected to fail")
+	}
+}
+
+// Tests if the exit status of a script becomes the exit code.
+func TestScriptExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"fail": "#!/bin/sh\nexit 3\n",
+		"kill": "#!/bin/sh\nkill -TERM $$\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	if err := c.LoadScriptDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]int{"fail": 3, "kill": 128 + 15} {
+		stderr := captureStdErr(t)
+		code := captureExit(t)
+		c.ParseAndRun([]string{name})
+		if *code != expected {
+			t.Errorf("%s: expected exit code %v, found %v", name, expected, *code)
+		}
+		if stderr.String() != "" {
+			t.Errorf("%s: no FATAL message is expected, found %q", name, stderr.String())
+		}
+	}
+}
+
+// Tests if a script reads the input set by SetInput, after what was
+// read by the prompts.
+func TestScriptInput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "upper"), []byte("#!/bin/sh\ntr a-z A-Z\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	if err := c.LoadScriptDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	stdout := captureStdOutput(t)
+	c.SetInput(strings.NewReader("answer\nhello\n"))
+	if line, err := c.Stdin().ReadString('\n'); err != nil || line != "answer\n" {
+		t.Fatalf("expected the answer to a prompt, found %q, %v", line, err)
+	}
+
+	c.ParseAndRun([]string{"upper"})
+	if stdout.String() != "HELLO\n" {
+		t.Errorf("expected the rest of the input, found %q", stdout.String())
+	}
+}
+
+// Tests if nothing is registered when a script name is taken, and if
+// the symbolic links are followed.
+func TestLoadScriptDirAtomic(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	dir, other := t.TempDir(), t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "a.sh"), filepath.Join(dir, "b.sh"), filepath.Join(dir, "b.py"), filepath.Join(other, "c.sh")} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n# a script\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	if err := c.LoadScriptDir(dir); err == nil || !strings.Contains(err.Error(), "'b'") {
+		t.Errorf("expected b to be taken, found %v", err)
+	}
+	if len(c.list) != 0 {
+		t.Errorf("nothing is expected to be registered, found %d commands", len(c.list))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "b.py")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(other, "c.sh"), filepath.Join(dir, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(other, "missing"), filepath.Join(dir, "d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadScriptDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, subcmd := range c.list {
+		names = append(names, subcmd.name)
+	}
+	if strings.Join(names, " ") != "a b c" {
+		t.Errorf("expected the scripts a, b and the linked c, found %q", names)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"fmt"
+)
+
+// SelfTester is implemented by sub-commands which can check their
+// subsystems, e.g. the connectivity to a server, see RunSelfTests.
+type SelfTester interface {
+	SelfTest() error
+}
+
+// Returns the self-test of command, or nil if it has none.
+func selfTester(command interface{}) SelfTester {
+	switch cmd := command.(type) {
+	case *outputCmd:
+		return selfTester(cmd.command)
+	case *structCmd:
+		return selfTester(cmd.v.Addr().Interface())
+	case SelfTester:
+		return cmd
+	}
+	return nil
+}
+
+// Runs the self-test of each registered sub-command implementing
+// SelfTester, in the order of registration, returning the failures
+// prefixed with the names of the sub-commands. Forwarding sub-commands
+// are skipped, their targets are tested on their own.
+func (c *Commands) RunSelfTests() []error {
+	var errs []error
+	c.walk(func(subcmd *cmdInstance) error {
+		if subcmd.forward != "" {
+			return nil
+		}
+		if st := selfTester(subcmd.command); st != nil {
+			if err := st.SelfTest(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", subcmd.pathName(), err))
+			}
+		}
+		return nil
+	})
+	return errs
+}
+
+// Registers the `selftest` sub-command, which runs the self-tests of
+// the sub-commands and fails if any of them fails.
+func (c *Commands) EnableSelfTestCommand() {
+	c.On("selftest", "运行子命令的自检", &selfTestCmd{c: c}, nil)
+}
+
+// selfTestCmd is the sub command registered by EnableSelfTestCommand.
+type selfTestCmd struct {
+	c *Commands
+}
+
+func (cmd *selfTestCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *selfTestCmd) Run(args []string) error {
+	errs := cmd.c.RunSelfTests()
+	for _, err := range errs {
+		Println(err)
+	}
+	if len(errs) > 0 {
+		return &Error{Code: 1, Message: fmt.Sprintf("%d 项自检失败", len(errs))}
+	}
+	Println("自检通过")
+	return nil
+}
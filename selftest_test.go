@@ -0,0 +1,79 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// testSelfTestCmd is a test sub command whose self-test returns err.
+type testSelfTestCmd struct {
+	err    error
+	tested bool
+}
+
+func (cmd *testSelfTestCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testSelfTestCmd) Run(args []string) error {
+	return nil
+}
+
+func (cmd *testSelfTestCmd) SelfTest() error {
+	cmd.tested = true
+	return cmd.err
+}
+
+// Tests if the self-tests run and their failures are collected.
+func TestRunSelfTests(t *testing.T) {
+	stdout := captureStdOutput(t)
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	ok := &testSelfTestCmd{}
+	errDown := errors.New("server is down")
+	down := &testSelfTestCmd{err: errDown}
+	c.On("ok", "", ok, []string{})
+	c.On("down", "", down, []string{})
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.OnForward("d", "", "down", nil)
+	c.EnableSelfTestCommand()
+
+	errs := c.RunSelfTests()
+	if !ok.tested || !down.tested {
+		t.Error("expected both self-tests to run")
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errDown) || errs[0].Error() != "down: server is down" {
+		t.Fatalf("expected the failure of down only, found %v", errs)
+	}
+
+	c.ParseAndRun([]string{"selftest"})
+	if *code != 1 || stdout.String() != "down: server is down\n" {
+		t.Errorf("expected the failure and exit code 1, found %q and %d", stdout.String(), *code)
+	}
+
+	down.err = nil
+	stdout.Reset()
+	*code = -100
+	c.ParseAndRun([]string{"selftest"})
+	if *code != -100 || !strings.Contains(stdout.String(), "自检通过") {
+		t.Errorf("expected to pass, found %q and %d", stdout.String(), *code)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"io"
+)
+
+// Replaces the value of a sensitive flag wherever it is shown.
+const redacted = "******"
+
+// Marks the named flag of the named sub-command, or the global flag if
+// cmdName is empty, as holding a secret, e.g. a password read from the
+// environment into its default. Its value and default are shown as
+// ****** in the usage, the metadata, the check mode report and the env
+// sub-command, while the sub-command still gets the real value.
+func (c *Commands) MarkFlagSensitive(cmdName, flagName string) {
+	if cmdName == "" {
+		if c.sensitiveFlags == nil {
+			c.sensitiveFlags = make(map[string]bool)
+		}
+		c.sensitiveFlags[flagName] = true
+		return
+	}
+	subcmd := c.mustLookup(cmdName)
+	if subcmd.sensitiveFlags == nil {
+		subcmd.sensitiveFlags = make(map[string]bool)
+	}
+	subcmd.sensitiveFlags[flagName] = true
+}
+
+// Reports whether the named flag of subcmd, or the global flag if
+// subcmd is nil, is sensitive. The flags of a forwarding sub-command
+// are also those of its target.
+func (c *Commands) isSensitive(subcmd *cmdInstance, name string) bool {
+	if subcmd == nil {
+		return c.sensitiveFlags[name]
+	}
+	if subcmd.sensitiveFlags[name] {
+		return true
+	}
+	target, _ := c.resolve(subcmd, nil)
+	return target != nil && target.sensitiveFlags[name]
+}
+
+// Replaces the non-zero defaults of the sensitive flags of subcmd in fs,
+// which must not be used for parsing afterwards.
+func (c *Commands) redactDefaults(subcmd *cmdInstance, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if !isZeroDefault(f.DefValue) && c.isSensitive(subcmd, f.Name) {
+			f.DefValue = redacted
+		}
+	})
+}
+
+// Prints the defaults of the global flags to w like PrintDefaults, with
+// those of the sensitive flags redacted, leaving c.flags untouched.
+func (c *Commands) printGlobalDefaults(w io.Writer) {
+	fs := flag.NewFlagSet(c.program, flag.ContinueOnError)
+	c.flags.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+		fs.Lookup(f.Name).DefValue = f.DefValue
+	})
+	c.redactDefaults(nil, fs)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// Reports whether s is the default of a flag left at its zero value.
+func isZeroDefault(s string) bool {
+	switch s {
+	case "", "0", "false", "0s", "[]":
+		return true
+	}
+	return false
+}
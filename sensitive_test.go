@@ -0,0 +1,122 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// testSecretCmd is a test sub command whose -password defaults to a
+// secret, e.g. read from the environment.
+type testSecretCmd struct {
+	password string
+}
+
+func (cmd *testSecretCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&cmd.password, "password", "from-env", "the password")
+	fs.String("user", "admin", "the user")
+	return fs
+}
+
+func (cmd *testSecretCmd) Run(args []string) error {
+	return nil
+}
+
+// Tests if the values of the sensitive flags are redacted wherever they
+// are shown, but still reach the command.
+func TestMarkFlagSensitive(t *testing.T) {
+	stdout := captureStdOutput(t)
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.flags.String("dsn", "", "")
+	if err := c.flags.Parse([]string{"-dsn=mysql://root:pw@db"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &testSecretCmd{}
+	c.On("login", "", cmd, []string{})
+	c.OnForward("l", "", "login", nil)
+	c.MarkFlagSensitive("login", "password")
+	c.MarkFlagSensitive("", "dsn")
+	c.EnableEnvCommand()
+
+	var out bytes.Buffer
+	c.SubcommandUsage(c.lookup("l"))
+	out.WriteString(stderr.String())
+	metas, _ := c.FlagMetadata("login")
+	for _, meta := range metas {
+		if meta.Sensitive != (meta.Name == "password") {
+			t.Errorf("flag %s: unexpected sensitive %v", meta.Name, meta.Sensitive)
+		}
+		out.WriteString(meta.Default + "\n")
+	}
+	c.DumpJSON(&out)
+	schema, _ := c.FlagJSONSchema("login")
+	out.Write(schema)
+	c.ParseAndRun([]string{"env"})
+	out.WriteString(stdout.String())
+
+	for _, s := range []string{"from-env", "root:pw"} {
+		if strings.Contains(out.String(), s) {
+			t.Errorf("the secret %q is not expected to be shown, found %q", s, out.String())
+		}
+	}
+	for _, s := range []string{`(default "******")`, `"writeOnly": true`, "-dsn=******", `"default": "admin"`} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q, found %q", s, out.String())
+		}
+	}
+
+	stdout.Reset()
+	c.EnableCheckMode("check")
+	c.flags.Parse([]string{"-check"})
+	c.Parse([]string{"l", "-password", "s3cr3t"})
+	if strings.Contains(stdout.String(), "s3cr3t") || !strings.Contains(stdout.String(), `"password": "******"`) {
+		t.Errorf("expected the password to be redacted in the check report, found %q", stdout.String())
+	}
+
+	c.checkMode = false
+	c.ParseAndRun([]string{"l", "-password", "s3cr3t"})
+	if cmd.password != "s3cr3t" {
+		t.Errorf("expected the command to get the password, found %q", cmd.password)
+	}
+}
+
+// Tests if the defaults of the sensitive global flags are redacted in
+// the top-level usage, with or without sub-commands.
+func TestMarkFlagSensitiveGlobalUsage(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.flags.String("dsn", "mysql://root:pw@db", "the database")
+	c.MarkFlagSensitive("", "dsn")
+	for _, on := range []bool{false, true} {
+		if on {
+			c.On("login", "", &testSecretCmd{}, []string{})
+		}
+		stderr.Reset()
+		c.Usage()
+		if strings.Contains(stderr.String(), "root:pw") || !strings.Contains(stderr.String(), `the database (default "******")`) {
+			t.Errorf("expected the default of -dsn to be redacted, found %q", stderr.String())
+		}
+	}
+	if f := c.flags.Lookup("dsn"); f.DefValue != "mysql://root:pw@db" {
+		t.Errorf("the global flags are not expected to change, found %q", f.DefValue)
+	}
+}
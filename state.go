@@ -0,0 +1,42 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+// StateAware is implemented by sub-commands which use the state of the
+// application set by SetAppState.
+type StateAware interface {
+	SetState(state interface{})
+}
+
+// Sets the state of the application, e.g. an opened client, passed to
+// the running sub-command before its Run if it implements StateAware.
+func (c *Commands) SetAppState(state interface{}) {
+	c.appState = state
+}
+
+// Passes state to command if it implements StateAware.
+func setState(command interface{}, state interface{}) {
+	if sa, ok := command.(StateAware); ok {
+		sa.SetState(state)
+	}
+}
+
+func (cmd *outputCmd) SetState(state interface{}) {
+	setState(cmd.command, state)
+}
+
+func (cmd *structCmd) SetState(state interface{}) {
+	setState(cmd.v.Addr().Interface(), state)
+}
@@ -0,0 +1,63 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"testing"
+)
+
+type testClient struct {
+	addr string
+}
+
+// testStateCmd is a test sub command using the application state.
+type testStateCmd struct {
+	client *testClient
+	addr   string
+}
+
+func (cmd *testStateCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testStateCmd) SetState(state interface{}) {
+	cmd.client, _ = state.(*testClient)
+}
+
+func (cmd *testStateCmd) Run(args []string) error {
+	cmd.addr = cmd.client.addr
+	return nil
+}
+
+// Tests if the application state is passed to a StateAware command.
+func TestAppState(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStateCmd{}
+	c.On("state", "", cmd, []string{})
+	c.OnForward("s", "", "state", nil)
+	c.SetAppState(&testClient{addr: "127.0.0.1:80"})
+	c.ParseAndRun([]string{"state"})
+	if cmd.addr != "127.0.0.1:80" {
+		t.Errorf("expected the address of the client, found %q", cmd.addr)
+	}
+
+	cmd.addr = ""
+	c.SetAppState(&testClient{addr: "127.0.0.1:8080"})
+	c.ParseAndRun([]string{"s"})
+	if cmd.addr != "127.0.0.1:8080" {
+		t.Errorf("expected the state to be passed through the forward, found %q", cmd.addr)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"strings"
+)
+
+// Sets the reader prompts, confirmations and the arguments enabled by
+// EnableStdinArgs are read from, instead of StdInput, e.g. to feed the
+// input in tests or servers.
+func (c *Commands) SetInput(r io.Reader) {
+	c.input = r
+}
+
+// Returns the reader sub-commands should read their input from, see
+// SetInput. It is buffered and shared with the prompts, so that no
+// input read ahead by a prompt is lost.
+func (c *Commands) Stdin() *bufio.Reader {
+	source := c.inputSource()
+	if c.reader == nil || c.readerSource != source {
+		c.reader = bufio.NewReader(source)
+		c.readerSource = source
+	}
+	return c.reader
+}
+
+// Returns the reader set by SetInput, or StdInput.
+func (c *Commands) inputSource() io.Reader {
+	if c.input != nil {
+		return c.input
+	}
+	return StdInput
+}
+
+// StdinConsumer is implemented by filter-like sub-commands which take
+// their primary input from stdin. When the input isn't a terminal, e.g.
+// a pipe, ConsumeStdin is called with it before Run, and Run isn't
+// called if it fails.
+type StdinConsumer interface {
+	ConsumeStdin(r io.Reader) error
+}
+
+// Returns the StdinConsumer of command, or nil if it isn't one.
+func stdinConsumer(command interface{}) StdinConsumer {
+	switch cmd := command.(type) {
+	case *outputCmd:
+		return stdinConsumer(cmd.command)
+	case *structCmd:
+		return stdinConsumer(cmd.v.Addr().Interface())
+	case StdinConsumer:
+		return cmd
+	}
+	return nil
+}
+
+// Passes the input to command if it is a StdinConsumer and the input
+// is piped.
+func (c *Commands) consumeStdin(command interface{}) error {
+	sc := stdinConsumer(command)
+	if sc == nil || isTerminal(c.inputSource()) {
+		return nil
+	}
+	return sc.ConsumeStdin(c.Stdin())
+}
+
+// Adds a bool flag named flagName to every sub-command which, when
+// set, reads more arguments from the input and appends them to the
+// arguments passed to Run, like xargs. The arguments are separated by
+// newlines, or by null characters if nullDelim is true.
+func (c *Commands) EnableStdinArgs(flagName string, nullDelim bool) {
+	c.stdinArgsFlag = flagName
+	c.stdinArgsNull = nullDelim
+}
+
+// Defines the flag enabled by EnableStdinArgs in fs.
+func (c *Commands) stdinArgsFlags(fs *flag.FlagSet) {
+	if c.stdinArgsFlag != "" && fs.Lookup(c.stdinArgsFlag) == nil {
+		fs.Bool(c.stdinArgsFlag, false, "从标准输入读取更多的参数")
+	}
+}
+
+// Returns the arguments read from the input if the flag enabled by
+// EnableStdinArgs is set in fs.
+func (c *Commands) readStdinArgs(fs *flag.FlagSet) ([]string, error) {
+	if c.stdinArgsFlag == "" {
+		return nil, nil
+	}
+	if f := fs.Lookup(c.stdinArgsFlag); f == nil || f.Value.String() != "true" {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(c.Stdin())
+	if err != nil {
+		return nil, err
+	}
+	sep := "\n"
+	if c.stdinArgsNull {
+		sep = "\x00"
+	}
+	var args []string
+	for _, arg := range strings.Split(string(data), sep) {
+		if !c.stdinArgsNull {
+			arg = strings.TrimSuffix(arg, "\r")
+		}
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Sets StdInput to a reader of input until the test finishes.
+func fakeStdInput(t *testing.T, input string) {
+	old := StdInput
+	StdInput = strings.NewReader(input)
+	t.Cleanup(func() { StdInput = old })
+}
+
+// Tests if newline delimited arguments are read from stdin when the
+// flag is set.
+func TestStdinArgs(t *testing.T) {
+	fakeStdInput(t, "b.txt\r\n\nc d.txt\n")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.EnableStdinArgs("stdin", false)
+	c.Parse([]string{"command1", "-stdin", "a.txt"})
+	expected := []string{"a.txt", "b.txt", "c d.txt"}
+	if !reflect.DeepEqual(c.args, expected) {
+		t.Errorf("expected %v, found %v", expected, c.args)
+	}
+
+	c.Parse([]string{"command1", "a.txt"})
+	if !reflect.DeepEqual(c.args, []string{"a.txt"}) {
+		t.Errorf("stdin is not expected to be read without the flag, found %v", c.args)
+	}
+}
+
+// Tests if null delimited arguments are read from stdin.
+func TestStdinArgsNullDelim(t *testing.T) {
+	fakeStdInput(t, "a\nb.txt\x00c.txt\x00")
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.EnableStdinArgs("0", true)
+	c.Parse([]string{"command1", "-0"})
+	expected := []string{"a\nb.txt", "c.txt"}
+	if !reflect.DeepEqual(c.args, expected) {
+		t.Errorf("expected %v, found %v", expected, c.args)
+	}
+}
+
+// Tests if prompts and stdin arguments read from the input set.
+func TestSetInput(t *testing.T) {
+	captureStdErr(t)
+	old := isTerminal
+	isTerminal = func(v interface{}) bool {
+		_, ok := v.(*strings.Reader)
+		return ok
+	}
+	defer func() { isTerminal = old }()
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testStringCmd{}
+	c.On("login", "", cmd, []string{"token"})
+	c.SetPromptRequired(true)
+	c.EnableStdinArgs("stdin", false)
+	c.SetInput(strings.NewReader("secret\na.txt\nb.txt\n"))
+	if err := c.parse([]string{"login", "-stdin"}); err != nil {
+		t.Fatal(err)
+	}
+	if *cmd.token != "secret" {
+		t.Errorf("expected the token read from the input, found %q", *cmd.token)
+	}
+	expected := []string{"a.txt", "b.txt"}
+	if !reflect.DeepEqual(c.args, expected) {
+		t.Errorf("expected the arguments %v read after the prompt, found %v", expected, c.args)
+	}
+}
+
+// testFilterCmd is a test sub command upper-casing its piped input.
+type testFilterCmd struct {
+	input string
+	run   bool
+}
+
+func (cmd *testFilterCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	return fs
+}
+
+func (cmd *testFilterCmd) ConsumeStdin(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return &Error{Code: 2, Message: "输入为空"}
+	}
+	cmd.input = strings.ToUpper(string(data))
+	return nil
+}
+
+func (cmd *testFilterCmd) Run(args []string) error {
+	cmd.run = true
+	return nil
+}
+
+// Tests if a piped input is passed to a StdinConsumer before it runs.
+func TestStdinConsumer(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testFilterCmd{}
+	c.On("upper", "", cmd, []string{})
+	c.SetInput(strings.NewReader("a\nb\n"))
+	c.ParseAndRun([]string{"upper"})
+	if cmd.input != "A\nB\n" || !cmd.run {
+		t.Errorf("expected the input to be consumed before running, found %q %v", cmd.input, cmd.run)
+	}
+
+	cmd.input, cmd.run = "", false
+	c.SetInput(strings.NewReader(""))
+	c.ParseAndRun([]string{"upper"})
+	if cmd.run || *code != 2 {
+		t.Errorf("expected to fail with code 2 without running, found %v %d", cmd.run, *code)
+	}
+
+	cmd.input, cmd.run = "", false
+	fakeTerminal(t, "a\n")
+	c.SetInput(nil)
+	c.ParseAndRun([]string{"upper"})
+	if cmd.input != "" || !cmd.run {
+		t.Errorf("expected a terminal not to be consumed, found %q %v", cmd.input, cmd.run)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// structFlag is a flag bound to a field of the struct of a structCmd.
+type structFlag struct {
+	field    int
+	name     string
+	usage    string
+	def      string
+	hasDef   bool
+	required bool
+}
+
+// structCmd is a sub command registered by OnStruct.
+type structCmd struct {
+	v reflect.Value
+	// the fields as registered, restored before each parse
+	init  reflect.Value
+	run   func(args []string) error
+	flags []structFlag
+}
+
+func (cmd *structCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	if err := cmd.define(fs); err != nil {
+		// checked by OnStruct
+		panic(err)
+	}
+	return fs
+}
+
+// Defines the flags in fs, resetting the fields to their defaults.
+func (cmd *structCmd) define(fs *flag.FlagSet) error {
+	for _, sf := range cmd.flags {
+		field := cmd.v.Field(sf.field)
+		field.Set(cmd.init.Field(sf.field))
+		p := field.Addr().Interface()
+		if err := defineVar(fs, p, sf.name, sf.usage); err != nil {
+			return err
+		}
+		if sf.hasDef {
+			f := fs.Lookup(sf.name)
+			if err := f.Value.Set(sf.def); err != nil {
+				return fmt.Errorf("选项 '%s' 的默认值 '%s' 无效: %s", sf.name, sf.def, err)
+			}
+			f.DefValue = f.Value.String()
+		}
+	}
+	return nil
+}
+
+func (cmd *structCmd) Run(args []string) error {
+	return cmd.run(args)
+}
+
+// Registers a sub-command described by the struct v points to, which
+// must have a `Run(args []string) error` method. Each field tagged
+// `flag:"name"` becomes a flag, with the optional tags `usage`,
+// `default` and `required:"true"`. Blank fields carry the description
+// of the sub-command in the `description` tag, and an example in the
+// `example` tag:
+//
+//	type deployCmd struct {
+//		_   struct{} `description:"部署应用" example:"app deploy -env prod"`
+//		Env string   `flag:"env" usage:"部署的环境" required:"true"`
+//		N   int      `flag:"n" usage:"副本数" default:"1"`
+//	}
+func (c *Commands) OnStruct(name string, v interface{}) error {
+	runner, ok := v.(interface{ Run(args []string) error })
+	if !ok {
+		return errors.New("命令 '" + name + "' 缺少 Run(args []string) error 方法")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("命令 '" + name + "' 必须是结构体指针")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	cmd := &structCmd{v: rv, init: reflect.New(rt).Elem(), run: runner.Run}
+	cmd.init.Set(rv)
+	var description string
+	var examples, required []string
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Name == "_" {
+			description += field.Tag.Get("description")
+			if example, ok := field.Tag.Lookup("example"); ok {
+				examples = append(examples, example)
+			}
+			continue
+		}
+
+		flagName, ok := field.Tag.Lookup("flag")
+		if !ok {
+			for _, key := range []string{"usage", "default", "required"} {
+				if _, ok := field.Tag.Lookup(key); ok {
+					return fmt.Errorf("命令 '%s' 的字段 '%s' 有 %s 标签, 但缺少 flag 标签", name, field.Name, key)
+				}
+			}
+			continue
+		}
+		if flagName == "" || !field.IsExported() {
+			return fmt.Errorf("命令 '%s' 的字段 '%s' 不能作为选项", name, field.Name)
+		}
+		sf := structFlag{field: i, name: flagName, usage: field.Tag.Get("usage")}
+		sf.def, sf.hasDef = field.Tag.Lookup("default")
+		if s, ok := field.Tag.Lookup("required"); ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("命令 '%s' 的字段 '%s' 的 required 标签 '%s' 无效", name, field.Name, s)
+			}
+			sf.required = b
+		}
+		if sf.required {
+			required = append(required, flagName)
+		}
+		cmd.flags = append(cmd.flags, sf)
+	}
+
+	// report unsupported types and invalid defaults now rather than
+	// when parsing
+	if err := cmd.define(flag.NewFlagSet(name, flag.ContinueOnError)); err != nil {
+		return fmt.Errorf("命令 '%s': %s", name, err)
+	}
+	if c.lookup(name) != nil {
+		return errors.New("命令 '" + name + "' 已存在")
+	}
+	c.On(name, description, cmd, required)
+	if len(examples) > 0 {
+		c.SetExamples(name, examples...)
+	}
+	return nil
+}
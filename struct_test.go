@@ -0,0 +1,114 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDeployCmd is a test sub command described by its struct.
+type testDeployCmd struct {
+	_        struct{}      `description:"deploys the application" example:"cmd deploy -env prod"`
+	_        struct{}      `example:"cmd deploy -env test -n 3"`
+	Env      string        `flag:"env" usage:"the environment" required:"true"`
+	N        int           `flag:"n" usage:"the number of replicas" default:"1"`
+	Timeout  time.Duration `flag:"timeout" default:"1m"`
+	Internal string
+
+	args []string
+}
+
+func (cmd *testDeployCmd) Run(args []string) error {
+	cmd.args = args
+	return nil
+}
+
+// Tests if a command is registered and parsed from its struct.
+func TestOnStruct(t *testing.T) {
+	captureStdErr(t)
+	code := captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	cmd := &testDeployCmd{}
+	if err := c.OnStruct("deploy", cmd); err != nil {
+		t.Fatal(err)
+	}
+	subcmd := c.lookup("deploy")
+	if subcmd.description != "deploys the application" {
+		t.Errorf("unexpected description %q", subcmd.description)
+	}
+	if !reflect.DeepEqual(subcmd.examples, []string{"cmd deploy -env prod", "cmd deploy -env test -n 3"}) {
+		t.Errorf("unexpected examples %q", subcmd.examples)
+	}
+	if !reflect.DeepEqual(subcmd.requiredFlags, []string{"env"}) {
+		t.Errorf("unexpected required flags %q", subcmd.requiredFlags)
+	}
+
+	c.ParseAndRun([]string{"deploy", "-env", "prod", "arg"})
+	if cmd.Env != "prod" || cmd.N != 1 || cmd.Timeout != time.Minute || !reflect.DeepEqual(cmd.args, []string{"arg"}) {
+		t.Errorf("unexpected command after running: %+v", cmd)
+	}
+
+	c.Parse([]string{"deploy", "-n", "3"})
+	if *code != 1 {
+		t.Errorf("the required flag env is expected to be missing, found exit code %v", *code)
+	}
+}
+
+type testNoRunCmd struct {
+	Env string `flag:"env"`
+}
+
+type testBadTypeCmd struct {
+	testDeployCmd
+	Tags []string `flag:"tags"`
+}
+
+type testBadDefaultCmd struct {
+	testDeployCmd
+	Count int `flag:"count" default:"many"`
+}
+
+type testBadTagCmd struct {
+	testDeployCmd
+	Count int `usage:"the count"`
+}
+
+type testBadRequiredCmd struct {
+	testDeployCmd
+	Count int `flag:"count" required:"yes"`
+}
+
+// Tests if unsupported structs and tags are reported on registration.
+func TestOnStructErrors(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	for _, v := range []interface{}{
+		&testNoRunCmd{},
+		&testBadTypeCmd{},
+		&testBadDefaultCmd{},
+		&testBadTagCmd{},
+		&testBadRequiredCmd{},
+	} {
+		if err := c.OnStruct("bad", v); err == nil {
+			t.Errorf("%T is expected to fail", v)
+		}
+	}
+	if len(c.list) != 0 {
+		t.Errorf("no command is expected to be registered, found %v", len(c.list))
+	}
+}
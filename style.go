@@ -0,0 +1,139 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlagValueStyle is how the values of non-bool flags must be given.
+type FlagValueStyle int
+
+const (
+	// Both -flag=value and -flag value are accepted, as by the flag
+	// package.
+	StyleAny FlagValueStyle = iota
+	// Only -flag=value is accepted.
+	StyleEquals
+	// Only -flag value is accepted.
+	StyleSpace
+)
+
+// Sets how the values of the non-bool flags of sub-commands must be
+// given, to avoid values being mistaken for positional arguments or
+// the other way round. Bool flags never take a separate value, so
+// they are not affected.
+func (c *Commands) SetFlagValueStyle(style FlagValueStyle) {
+	c.flagValueStyle = style
+}
+
+// Checks that the flags in args use the flag value style, following
+// the rules of the flag package to tell flags from values and
+// positional arguments.
+func (c *Commands) checkFlagStyle(fs *flag.FlagSet, args []string) error {
+	if c.flagValueStyle == StyleAny {
+		return nil
+	}
+	for i := 0; i < len(args); i++ {
+		name, hasValue, ok := splitFlag(args[i])
+		if !ok {
+			break
+		}
+		f := fs.Lookup(name)
+		if f == nil || isBoolFlag(f) {
+			continue
+		}
+		if hasValue && c.flagValueStyle == StyleSpace {
+			return fmt.Errorf("选项 -%s 的值必须用空格分隔: -%s 值", name, name)
+		}
+		if !hasValue {
+			if c.flagValueStyle == StyleEquals {
+				return fmt.Errorf("选项 -%s 的值必须用等号指定: -%s=值", name, name)
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// Requires the flags of sub-commands to be given before the positional
+// arguments. The flag package stops parsing flags at the first
+// positional argument, so a flag given after it is silently taken as
+// an argument, this reports it as an error instead. Flags after the
+// "--" terminator, and negative numbers, are still arguments.
+func (c *Commands) SetFlagsBeforeArgs(b bool) {
+	c.flagsBeforeArgs = b
+}
+
+// Checks that no flag in args follows a positional argument if
+// required by SetFlagsBeforeArgs.
+func (c *Commands) checkFlagOrder(fs *flag.FlagSet, args []string) error {
+	if !c.flagsBeforeArgs {
+		return nil
+	}
+	i := 0
+	for ; i < len(args); i++ {
+		name, hasValue, ok := splitFlag(args[i])
+		if !ok {
+			break
+		}
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+			i++
+		}
+	}
+	if i >= len(args) || args[i] == "--" {
+		return nil
+	}
+	for _, arg := range args[i+1:] {
+		if arg == "--" {
+			break
+		}
+		if _, err := strconv.ParseFloat(arg, 64); err == nil {
+			continue
+		}
+		if name, _, ok := splitFlag(arg); ok {
+			return fmt.Errorf("选项 -%s 必须在参数 '%s' 之前", name, args[i])
+		}
+	}
+	return nil
+}
+
+// Splits a flag argument into the flag name and whether the value is
+// attached with "=". ok is false if arg ends the flags, i.e. it is a
+// positional argument or the "--" terminator.
+func splitFlag(arg string) (name string, hasValue, ok bool) {
+	if len(arg) < 2 || arg[0] != '-' {
+		return "", false, false
+	}
+	name = arg[1:]
+	if name[0] == '-' {
+		name = name[1:]
+		if name == "" {
+			return "", false, false
+		}
+	}
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], true, true
+	}
+	return name, false, true
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,126 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+// Tests if each style accepts and rejects the appropriate forms.
+func TestFlagValueStyle(t *testing.T) {
+	for _, test := range []struct {
+		style FlagValueStyle
+		args  []string
+		code  int
+	}{
+		{StyleAny, []string{"all", "-string=a", "-int", "1"}, -100},
+		{StyleEquals, []string{"all", "-string=a", "--int=1", "-bool", "arg"}, -100},
+		{StyleEquals, []string{"all", "-string", "a"}, 2},
+		{StyleEquals, []string{"all", "arg", "-string", "a"}, -100},
+		{StyleSpace, []string{"all", "-string", "a", "--int", "1", "-bool=false"}, -100},
+		{StyleSpace, []string{"all", "-string", "-int=1"}, -100},
+		{StyleSpace, []string{"all", "-string=a"}, 2},
+		{StyleSpace, []string{"all", "--", "-string=a"}, -100},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("all", "", &testAllFlagsCmd{}, []string{})
+		c.SetFlagValueStyle(test.style)
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("style %v %v: expected exit code %v, found %v", test.style, test.args, test.code, *code)
+		}
+	}
+}
+
+// Tests if only the arguments given by the user are held to the style,
+// the forwarded ones being checked by Validate.
+func TestFlagValueStyleForward(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"short", "-int=1", "arg"}, -100},
+		{[]string{"short", "-int", "1"}, 2},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("all", "", &testAllFlagsCmd{}, []string{})
+		c.OnForward("short", "", "all", []string{"-string", "a"})
+		c.SetFlagValueStyle(StyleEquals)
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "快捷命令 'short' 的预设参数无效") {
+			t.Errorf("expected the forwarded arguments to be invalid, found %v", err)
+		}
+	}
+}
+
+// Tests if flags after positional arguments are rejected.
+func TestFlagsBeforeArgs(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"all", "-string", "a", "-bool", "arg1", "arg2"}, -100},
+		{[]string{"all", "-string=a", "arg", "-1"}, -100},
+		{[]string{"all", "arg", "--", "-string", "a"}, -100},
+		{[]string{"all", "--", "arg", "-bool"}, -100},
+		{[]string{"all", "arg", "-bool"}, 2},
+		{[]string{"all", "-int", "1", "arg", "--string=a"}, 2},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("all", "", &testAllFlagsCmd{}, []string{})
+		c.SetFlagsBeforeArgs(true)
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+	}
+}
+
+// Tests if only the arguments given by the user are held to the order,
+// the forwarded ones being checked by Validate.
+func TestFlagsBeforeArgsForward(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"short", "-bool"}, -100},
+		{[]string{"short", "arg", "-bool"}, 2},
+	} {
+		captureStdErr(t)
+		code := captureExit(t)
+		c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+		c.On("all", "", &testAllFlagsCmd{}, []string{})
+		c.OnForward("short", "", "all", []string{"arg", "-string", "a"})
+		c.SetFlagsBeforeArgs(true)
+		c.Parse(test.args)
+		if *code != test.code {
+			t.Errorf("%v: expected exit code %v, found %v", test.args, test.code, *code)
+		}
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "快捷命令 'short' 的预设参数无效") {
+			t.Errorf("expected the forwarded arguments to be invalid, found %v", err)
+		}
+	}
+}
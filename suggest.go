@@ -0,0 +1,106 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"sort"
+)
+
+// The number of suggestions shown unless SetMaxSuggestions is called.
+const defaultMaxSuggestions = 3
+
+// The largest edit distance of a suggested sub-command.
+const suggestionDistance = 2
+
+// Suggests the sub-commands whose names are close to an unknown one,
+// e.g. status for stauts, when it is given.
+func (c *Commands) SetSuggestions(enabled bool) {
+	c.suggestions = enabled
+}
+
+// Limits the number of sub-commands suggested for an unknown one to n,
+// the closest first. n <= 0 restores the default of 3.
+func (c *Commands) SetMaxSuggestions(n int) {
+	c.maxSuggestions = n
+}
+
+// Returns the authorized sub-commands among candidates close to name,
+// sorted by distance then by name, at most as many as allowed.
+func (c *Commands) suggest(name string, candidates []*cmdInstance) []string {
+	if !c.suggestions {
+		return nil
+	}
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var matches []candidate
+	for _, subcmd := range candidates {
+		if !c.authorized(subcmd) {
+			continue
+		}
+		if d := editDistance(name, subcmd.name); d <= suggestionDistance {
+			matches = append(matches, candidate{subcmd.name, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	max := c.maxSuggestions
+	if max <= 0 {
+		max = defaultMaxSuggestions
+	}
+	var names []string
+	for i := 0; i < len(matches) && i < max; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// Returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
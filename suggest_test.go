@@ -0,0 +1,71 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests if the closest sub-commands are suggested, at most as many as
+// allowed.
+func TestSuggestions(t *testing.T) {
+	stderr := captureStdErr(t)
+	captureExit(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	for _, name := range []string{"stat", "state", "status", "stats", "start", "stop", "deploy"} {
+		c.On(name, "", &testCmd1{}, []string{})
+	}
+
+	c.Parse([]string{"stats1"})
+	if strings.Contains(stderr.String(), "您是不是要找") {
+		t.Errorf("no suggestion is expected unless enabled, found %q", stderr.String())
+	}
+
+	c.SetSuggestions(true)
+	if names := c.suggest("stats1", c.list); !reflect.DeepEqual(names, []string{"stats", "stat", "state"}) {
+		t.Errorf("expected the 3 closest commands, found %q", names)
+	}
+	c.SetMaxSuggestions(5)
+	if names := c.suggest("stats1", c.list); !reflect.DeepEqual(names, []string{"stats", "stat", "state", "status"}) {
+		t.Errorf("expected all close commands, found %q", names)
+	}
+	c.SetMaxSuggestions(1)
+	if names := c.suggest("deplyo", c.list); !reflect.DeepEqual(names, []string{"deploy"}) {
+		t.Errorf("expected deploy, found %q", names)
+	}
+	if names := c.suggest("unrelated", c.list); names != nil {
+		t.Errorf("no suggestion is expected, found %q", names)
+	}
+
+	stderr.Reset()
+	c.Parse([]string{"stats1"})
+	if !strings.Contains(stderr.String(), `未知的子命令: "stats1", 您是不是要找: stats`+"\n") {
+		t.Errorf("expected stats to be suggested, found %q", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "子命令列表:") || strings.Contains(stderr.String(), "查看使用方法") {
+		t.Errorf("expected the usage instead of the hint, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	c.SetSuppressUsageOnError(true)
+	c.Parse([]string{"stats1"})
+	if strings.Contains(stderr.String(), "子命令列表:") || !strings.Contains(stderr.String(), "运行 'cmd -h' 查看使用方法。") {
+		t.Errorf("expected the hint instead of the usage, found %q", stderr.String())
+	}
+}
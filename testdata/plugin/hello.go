@@ -0,0 +1,42 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The plugin loaded by TestLoadPlugin, registering the hello
+// sub-command.
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/mei-rune/command"
+)
+
+type helloCmd struct {
+	name *string
+}
+
+func (cmd *helloCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	cmd.name = fs.String("name", "world", "the name to greet")
+	return fs
+}
+
+func (cmd *helloCmd) Run(args []string) error {
+	command.Println("hello " + strings.Join(append([]string{*cmd.name}, args...), " "))
+	return nil
+}
+
+func RegisterCommands(c *command.Commands) {
+	c.On("hello", "says hello", &helloCmd{}, nil)
+}
@@ -0,0 +1,34 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The program run by TestLoadPlugin, loading the plugin given as its
+// first argument and running the rest of the arguments.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mei-rune/command"
+)
+
+func main() {
+	c := command.New("host", flag.NewFlagSet("host", flag.ContinueOnError))
+	if err := c.LoadPlugin(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c.ParseAndRun(os.Args[2:])
+}
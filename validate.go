@@ -0,0 +1,104 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Checks the configuration of the registered sub-commands, returning
+// all problems found joined into one error. It is meant to be called
+// from a test of the program rather than on every run.
+func (c *Commands) Validate() error {
+	var errs []error
+	c.walk(func(subcmd *cmdInstance) error {
+		if subcmd.forward != "" {
+			errs = append(errs, c.validateForward(subcmd)...)
+			return nil
+		}
+		errs = append(errs, c.validateRequired(subcmd)...)
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// Checks that the required flags of subcmd exist and have no default
+// value, which would be meaningless since the flag must be given.
+func (c *Commands) validateRequired(subcmd *cmdInstance) []error {
+	if len(subcmd.requiredFlags) == 0 || subcmd.rawArgs {
+		return nil
+	}
+	fs := subcmd.command.Flags(flag.NewFlagSet(subcmd.name, flag.ContinueOnError))
+	var errs []error
+	for _, name := range subcmd.requiredFlags {
+		f := fs.Lookup(name)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("命令 '%s' 的必需选项 -%s 不存在", subcmd.name, name))
+			continue
+		}
+		if !isZeroDefault(f.DefValue) {
+			value := f.DefValue
+			if c.isSensitive(subcmd, name) {
+				value = redacted
+			}
+			errs = append(errs, fmt.Errorf("命令 '%s' 的必需选项 -%s 有默认值 %q", subcmd.name, name, value))
+		}
+	}
+	return errs
+}
+
+// Checks that the target of a forwarding sub-command exists without
+// forwarding in a cycle, and that its forwarded arguments follow the
+// flag value style and order and don't violate a mutually exclusive
+// group of the target on their own.
+func (c *Commands) validateForward(subcmd *cmdInstance) []error {
+	if cycle := c.forwardCycle(subcmd); cycle != nil {
+		return []error{fmt.Errorf("快捷命令 '%s' 存在循环: %s", subcmd.name, strings.Join(cycle, " -> "))}
+	}
+	target, args := c.resolve(subcmd, nil)
+	if target == nil {
+		return []error{fmt.Errorf("快捷命令 '%s' 转发的目标命令不存在", subcmd.name)}
+	}
+	if target.rawArgs {
+		return nil
+	}
+
+	fs := target.command.Flags(flag.NewFlagSet(target.name, flag.ContinueOnError))
+	fs.SetOutput(io.Discard)
+	if err := c.checkFlagStyle(fs, args); err != nil {
+		return []error{fmt.Errorf("快捷命令 '%s' 的预设参数无效: %s", subcmd.name, err)}
+	}
+	if err := c.checkFlagOrder(fs, args); err != nil {
+		return []error{fmt.Errorf("快捷命令 '%s' 的预设参数无效: %s", subcmd.name, err)}
+	}
+	if err := fs.Parse(args); err != nil {
+		return []error{fmt.Errorf("快捷命令 '%s' 的预设参数无效: %s", subcmd.name, err)}
+	}
+
+	var errs []error
+	for _, ct := range target.constraints {
+		if ct.Kind != MutuallyExclusive {
+			continue
+		}
+		if err := checkConstraints(fs, []Constraint{ct}); len(err) > 0 {
+			errs = append(errs, fmt.Errorf("快捷命令 '%s' 的预设参数 %s", subcmd.name, ct))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,88 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+// Tests if shortcuts whose preset flags conflict are reported.
+func TestValidateForward(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("all", "", &testAllFlagsCmd{}, []string{})
+	c.MarkFlagsMutuallyExclusive("all", "int", "int64")
+	c.OnForward("good", "", "all", []string{"-int=1"})
+	if err := c.Validate(); err != nil {
+		t.Fatalf("no problem is expected, found %v", err)
+	}
+
+	c.OnForward("bad", "", "all", []string{"-int=1", "-int64=2"})
+	c.OnForward("invalid", "", "all", []string{"-unknown"})
+	c.OnForward("missing", "", "none", nil)
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("problems are expected")
+	}
+	for _, expected := range []string{"'bad'", "'invalid'", "'missing'"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected a problem with %s, found %q", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "'good'") {
+		t.Errorf("no problem with 'good' is expected, found %q", err)
+	}
+}
+
+// Tests if shortcuts forwarding in a cycle are reported instead of
+// looping forever.
+func TestForwardCycle(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("command1", "", &testCmd1{}, []string{})
+	c.OnForward("a", "", "b", nil)
+	c.OnForward("b", "", "a", nil)
+	c.OnForward("c", "", "a", nil)
+
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") || !strings.Contains(err.Error(), "'c'") {
+		t.Errorf("expected the cycle to be reported, found %v", err)
+	}
+
+	e := c.parse([]string{"c"})
+	if e == nil || !strings.Contains(e.Error(), "a -> b -> a") {
+		t.Errorf("expected the cycle to be reported by parse, found %v", e)
+	}
+}
+
+// Tests if required flags with a default value are reported.
+func TestValidateRequired(t *testing.T) {
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.On("good", "", &testAllFlagsCmd{}, []string{"bool", "func"})
+	if err := c.Validate(); err != nil {
+		t.Fatalf("no problem is expected, found %v", err)
+	}
+
+	c.On("bad", "", &testAllFlagsCmd{}, []string{"int", "missing"})
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("problems are expected")
+	}
+	for _, expected := range []string{"-int 有默认值 \"1\"", "-missing 不存在"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected a problem with %s, found %q", expected, err)
+		}
+	}
+}
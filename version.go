@@ -0,0 +1,75 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"runtime"
+)
+
+// buildInfo is printed by the version sub-command.
+type buildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Go      string `json:"go"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+}
+
+// Registers the `version` sub-command, which prints the version and
+// commit the program was built from, with the go version, os and arch.
+// They are printed as a JSON object if its -json flag is set, or if
+// the output format is json.
+func (c *Commands) SetVersion(version, commit string) {
+	c.version = &buildInfo{
+		Version: version,
+		Commit:  commit,
+		Go:      runtime.Version(),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+	}
+	c.On("version", "显示版本信息", &versionCmd{c: c}, nil)
+}
+
+// versionCmd is the sub command registered by SetVersion.
+type versionCmd struct {
+	c    *Commands
+	json bool
+}
+
+func (cmd *versionCmd) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.BoolVar(&cmd.json, "json", false, "以 JSON 格式输出")
+	return fs
+}
+
+func (cmd *versionCmd) Run(args []string) error {
+	if cmd.json || cmd.c.outputFormat == "json" {
+		data, err := json.Marshal(cmd.c.version)
+		if err != nil {
+			return err
+		}
+		Println(string(data))
+		return nil
+	}
+
+	info := cmd.c.version
+	Printf("%s %s", cmd.c.program, info.Version)
+	if info.Commit != "" {
+		Printf(" (%s)", info.Commit)
+	}
+	Printf(" %s %s/%s\n", info.Go, info.OS, info.Arch)
+	return nil
+}
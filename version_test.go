@@ -0,0 +1,46 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"runtime"
+	"testing"
+)
+
+// Tests if the version command prints the build info as text and json.
+func TestVersion(t *testing.T) {
+	stdout := captureStdOutput(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c.SetVersion("1.2.0", "abc123")
+	c.ParseAndRun([]string{"version"})
+	expected := "cmd 1.2.0 (abc123) " + runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH + "\n"
+	if stdout.String() != expected {
+		t.Errorf("expected %q, found %q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	c.ParseAndRun([]string{"version", "-json"})
+	var info map[string]string
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info["version"] != "1.2.0" || info["commit"] != "abc123" || info["go"] != runtime.Version() ||
+		info["os"] != runtime.GOOS || info["arch"] != runtime.GOARCH {
+		t.Errorf("unexpected build info %v", info)
+	}
+}
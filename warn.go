@@ -0,0 +1,44 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+)
+
+// Sets the writer of the warnings generated by the package itself,
+// such as deprecations, to keep them apart from the errors of the
+// commands. It defaults to StdErr.
+func (c *Commands) SetWarnOutput(w io.Writer) {
+	c.warnOutput = w
+}
+
+// Prints a warning of the package to the warning output.
+func (c *Commands) warn(msg string, args ...interface{}) {
+	w := c.warnOutput
+	if w == nil {
+		w = StdErr
+	}
+	fmt.Fprintf(w, "警告: "+msg, args...)
+	fmt.Fprintln(w, "")
+}
+
+// Marks the named sub-command as deprecated, a warning with the
+// message, e.g. which sub-command to use instead, is printed whenever
+// it runs.
+func (c *Commands) Deprecate(name, message string) {
+	c.mustLookup(name).deprecated = message
+}
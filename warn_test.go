@@ -0,0 +1,50 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// Tests if the deprecation warning goes to the warning output.
+func TestWarnOutput(t *testing.T) {
+	stderr := captureStdErr(t)
+
+	c := New("cmd", flag.NewFlagSet("cmd", flag.ContinueOnError))
+	c1 := &testCmd1{}
+	c.On("command1", "", c1, []string{})
+	c.Deprecate("command1", "请使用 command2")
+	c.ParseAndRun([]string{"command1"})
+	if !c1.run {
+		t.Error("command 'command1' was expected to run, but it didn't")
+	}
+	if !strings.Contains(stderr.String(), "命令 'command1' 已废弃, 请使用 command2") {
+		t.Errorf("expected the deprecation warning in stderr, found %q", stderr.String())
+	}
+
+	stderr.Reset()
+	var warnings bytes.Buffer
+	c.SetWarnOutput(&warnings)
+	c.ParseAndRun([]string{"command1"})
+	if stderr.String() != "" {
+		t.Errorf("no output to stderr is expected, found %q", stderr.String())
+	}
+	if warnings.String() != "警告: 命令 'command1' 已废弃, 请使用 command2\n" {
+		t.Errorf("expected the deprecation warning, found %q", warnings.String())
+	}
+}